@@ -0,0 +1,33 @@
+// docker run --init  -it  --rm --platform linux/amd64 --name "alertsink" --network faNetwork -v .:/app -v ./gocache:/go/pkg -v ./buildcache:/root/.cache/go-build -w /app golang:1.25-bookworm sh -c "go fmt 0alertSink.go && go run 0alertSink.go"
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"app/smtpsink"
+)
+
+const smtpAddress = "0.0.0.0:1025"
+const webhookAddress = "0.0.0.0:9012"
+
+func main() {
+	sink := smtpsink.New()
+	sink.OnMessage = func(message smtpsink.Message) {
+		fmt.Printf("Email from %s to %v:\n%s\n", message.From, message.To, message.Data)
+	}
+	go func() {
+		fmt.Printf("SMTP sink listening at %s\n", smtpAddress)
+		if err := sink.ListenAndServe(smtpAddress); err != nil {
+			fmt.Println(err.Error())
+		}
+	}()
+	http.HandleFunc("POST /", func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		fmt.Printf("Webhook %s:\n%s\n", request.URL.Path, body)
+	})
+	fmt.Printf("Webhook receiver listening at http://%s\n", webhookAddress)
+	http.ListenAndServe(webhookAddress, nil)
+}
@@ -0,0 +1,31 @@
+// docker run --init  -it  --rm --platform linux/amd64 --name "fa" --network faNetwork -v .:/app -v ./gocache:/go/pkg -v ./buildcache:/root/.cache/go-build -w /app golang:1.25-bookworm sh -c "go fmt 0fakeFusionAuth.go && go run 0fakeFusionAuth.go"
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"app/fakefusionauth"
+	"app/fusionauth"
+)
+
+const apiKey = "33052c8a-c283-4e96-9d2a-eb1215c69f8f-not-for-prod"
+const applicationId = "e9fdb985-9173-4e01-9d73-ac2d60d1dc8e"
+const tenantId = "d7d09513-a3f5-401c-9685-34ab6c552453"
+const maxResults = 10000
+const webhookUrl = "http://app:7777/webhooks/fusionauth"
+const webhookSecret = "b6d1a0e4-3c1f-4a8e-9d52-webhook-not-for-prod"
+
+func main() {
+	server := fakefusionauth.New(fakefusionauth.Options{
+		ApiKey:        apiKey,
+		MaxResults:    maxResults,
+		Tenants:       []fusionauth.Tenant{{Id: tenantId, Name: "Default"}},
+		Applications:  []fusionauth.Application{{Id: applicationId, Name: "Example app", TenantId: tenantId, Active: true}},
+		WebhookUrl:    webhookUrl,
+		WebhookSecret: webhookSecret,
+	})
+	fmt.Println("Fake FusionAuth listening at http://0.0.0.0:9011")
+	http.ListenAndServe("0.0.0.0:9011", server)
+}
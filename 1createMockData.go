@@ -3,61 +3,57 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"app/fusionauth"
+	"app/mockdata"
 )
 
-const fusionauthUrl = "http://fa:9011/api/user/registration"
+const fusionauthUrl = "http://fa:9011"
 const apiKey = "33052c8a-c283-4e96-9d2a-eb1215c69f8f-not-for-prod"
 const applicationId = "e9fdb985-9173-4e01-9d73-ac2d60d1dc8e"
-const numberOfUsersToCreate = 1000
 
 func main() {
-	client := &http.Client{}
-	for i := 1; i <= numberOfUsersToCreate; i++ {
-		email := fmt.Sprintf("%d@example.com", i)
-		requestBody := RegistrationRequest{
-			User: UserDetail{
-				Email:    email,
-				Password: "password",
-			},
-			Registration: ApplicationDetail{
-				ApplicationId: applicationId,
-			},
-		}
-		jsonData, _ := json.Marshal(requestBody)
-		request, _ := http.NewRequest("POST", fusionauthUrl, bytes.NewBuffer(jsonData))
-		request.Header.Set("Authorization", apiKey)
-		request.Header.Set("Content-Type", "application/json")
-		response, err := client.Do(request)
-		if err != nil {
-			fmt.Printf("networkError for user %s: %s\n", email, err.Error())
+	options := mockdata.DefaultOptions()
+	options.NumberOfUsers = 1000
+	flag.IntVar(&options.NumberOfUsers, "users", options.NumberOfUsers, "number of users to create")
+	batchSize := flag.Int("batchSize", 1000, "number of users sent in each import request")
+	concurrency := flag.Int("concurrency", 4, "number of import requests sent in parallel")
+	password := flag.String("password", "password", "password every imported user can log in with")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	client := fusionauth.NewClient(fusionauthUrl, apiKey)
+	client.HttpClient.Timeout = 5 * time.Minute
+	users := mockdata.ToFusionAuthUsers(mockdata.Generate(options), applicationId)
+	if err := fusionauth.SetImportPassword(users, *password); err != nil {
+		fmt.Printf("Error hashing the password: %s\n", err.Error())
+		os.Exit(1)
+	}
+	startTime := time.Now()
+	results := client.ImportUsersInBatches(ctx, users, *batchSize, *concurrency, func(batch fusionauth.BatchResult) {
+		if batch.Err != nil {
+			fmt.Printf("error importing users %d to %d: %s\n", batch.Start+1, batch.End, batch.Err.Error())
 			return
 		}
-		defer response.Body.Close()
-		responseBody, _ := io.ReadAll(response.Body)
-		if response.StatusCode < 200 || response.StatusCode >= 300 {
-			fmt.Printf("httpError %d for user %s: %s\n", response.StatusCode, email, string(responseBody))
-			return
+		fmt.Printf("Imported users %d to %d in %s\n", batch.Start+1, batch.End, batch.Duration.Round(time.Millisecond))
+	})
+	importedCount, failedBatchCount := 0, 0
+	for _, batch := range results {
+		if batch.Err != nil {
+			failedBatchCount++
+			continue
 		}
-		fmt.Println(string(responseBody))
-		fmt.Println("")
+		importedCount += batch.End - batch.Start
+	}
+	fmt.Printf("Imported %d of %d users in %s, %d of %d batches failed\n", importedCount, len(users), time.Since(startTime).Round(time.Millisecond), failedBatchCount, len(results))
+	if failedBatchCount > 0 {
+		os.Exit(1)
 	}
-}
-
-type RegistrationRequest struct {
-	User         UserDetail        `json:"user"`
-	Registration ApplicationDetail `json:"registration"`
-}
-
-type UserDetail struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-}
-
-type ApplicationDetail struct {
-	ApplicationId string `json:"applicationId"`
 }
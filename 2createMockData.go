@@ -0,0 +1,67 @@
+// docker run --init  -it  --rm --platform linux/amd64 --name "app" --network faNetwork -v .:/app -v ./gocache:/go/pkg -v ./buildcache:/root/.cache/go-build -w /app golang:1.25-bookworm sh -c "go fmt 2createMockData.go && go run 2createMockData.go"
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"app/fusionauth"
+	"app/mockdata"
+)
+
+const fusionauthUrl = "http://fa:9011"
+const apiKey = "33052c8a-c283-4e96-9d2a-eb1215c69f8f-not-for-prod"
+const applicationId = "e9fdb985-9173-4e01-9d73-ac2d60d1dc8e"
+const importBatchSize = 500
+
+func main() {
+	options := mockdata.DefaultOptions()
+	shouldPush := flag.Bool("push", false, "import the users into FusionAuth instead of writing users.json")
+	outputFile := flag.String("output", "users.json", "file to write the generated users to")
+	scenarioFile := flag.String("scenario", "", "scenario file describing user segments, for example scenario.json")
+	flag.IntVar(&options.NumberOfUsers, "users", options.NumberOfUsers, "number of users to generate")
+	flag.Uint64Var(&options.Seed, "seed", options.Seed, "random seed")
+	flag.Parse()
+
+	var users []mockdata.User
+	if *scenarioFile == "" {
+		users = mockdata.Generate(options)
+	} else {
+		scenario, err := mockdata.LoadScenario(*scenarioFile)
+		if err != nil {
+			fmt.Printf("Scenario error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		users = mockdata.GenerateScenario(scenario)
+	}
+	fmt.Printf("Generated %d users\n", len(users))
+	if !*shouldPush {
+		usersJson, err := json.MarshalIndent(users, "", "\t")
+		if err == nil {
+			err = os.WriteFile(*outputFile, usersJson, 0644)
+		}
+		if err != nil {
+			fmt.Printf("Error writing %s: %s\n", *outputFile, err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %d users to %s\n", len(users), *outputFile)
+		return
+	}
+
+	ctx := context.Background()
+	client := fusionauth.NewClient(fusionauthUrl, apiKey)
+	faUsers := mockdata.ToFusionAuthUsers(users, applicationId)
+	results := client.ImportUsersInBatches(ctx, faUsers, importBatchSize, 1, nil)
+	for _, batch := range results {
+		if batch.Err != nil {
+			fmt.Printf("error importing users %d to %d: %s\n", batch.Start+1, batch.End, batch.Err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Imported users %d to %d\n", batch.Start+1, batch.End)
+	}
+	fmt.Println("FusionAuth has no API to backdate login records, so logins were not pushed. Use users.json output or 2createMockData.sql for login history.")
+}
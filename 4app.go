@@ -1,61 +1,1591 @@
-// docker run --init  -it  --rm --platform linux/amd64 --name "app" -p 7777:7777 -v .:/app -v ./gocache:/go/pkg -v ./buildcache:/root/.cache/go-build -w /app golang:1.25-bookworm sh -c "go mod tidy && go fmt 4app.go && go run 4app.go"
+// docker run --init  -it  --rm --platform linux/amd64 --name "app" --network faNetwork -p 7777:7777 -v .:/app -v ./gocache:/go/pkg -v ./buildcache:/root/.cache/go-build -w /app golang:1.25-bookworm sh -c "go mod tidy && go fmt 4app.go && go run 4app.go"
 
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"html/template"
+	"io"
+	"maps"
 	"math"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"app/alerts"
+	"app/chartimage"
+	"app/config"
+	"app/digest"
+	"app/extract"
+	"app/fusionauth"
+	"app/geoip"
+	"app/mail"
+	"app/metrics"
+	"app/prometheus"
+	"app/report"
+	"app/schedule"
+	"app/security"
+	"app/webhook"
+
 	"github.com/samber/lo"
 )
 
+const usersFile = "users.json"
+const usersFilePollInterval = 2 * time.Second
+const geoIpDatabaseFile = "GeoLite2-City.mmdb"
+const unknownCountry = "Unknown"
+const topDomainCount = 20
+const maxDomainFilterOptions = 100
+const splitByVerified = "verified"
+const splitByCountry = "country"
+const splitByDomainType = "domainType"
+const splitByAttributePrefix = "attribute:"
+const noAttributeValue = "(none)"
+const unitUsers = "users"
+const unitLogins = "logins"
+const unitLoginsPerUser = "logins per user"
+const chartKindBar = "bar"
+const chartKindHeatmap = "heatmap"
+const chartKindTable = "table"
+const maxTableRows = 200
+const maxCachedViews = 100
+const maxExtractRuns = 20
+const extractTimeout = 30 * time.Minute
+const webhookRefreshDelay = time.Second
+const maxWebhookBodyBytes = 1 << 20
+const maxRetainedWebhookEvents = 10000
+const eventKeepAliveInterval = 30 * time.Second
+const eventRetryDelay = 5 * time.Second
+const alertNotifyTimeout = 30 * time.Second
+const digestSendTimeout = time.Minute
+const digestImageWidth = 600
+const defaultImageWidth = 800
+const minImageSize = 100
+const maxImageSize = 4000
+const heatmapRowHeight = 14
+const heatmapMargin = 80
+const reportImageWidth = 1000
+const maxReportTableRows = 40
+const readmeFile = "README.md"
+const pageFile = "5page.html"
+const staticAssetsDir = "assets"
+const staticChartsDir = "charts"
+const scriptDownloadTimeout = 30 * time.Second
+const renderUsage = `usage:
+  go run 4app.go render chart [-output file] [-width pixels] [-height pixels] [-query filters] <name>.png|<name>.svg
+  go run 4app.go render report [-output file] [-query filters]
+  go run 4app.go render static [-output directory]`
+
+var activeUserWindowDays = []int{1, 7, 30}
+
+var remoteScriptPattern = regexp.MustCompile(`<script src="(https?://[^"]+)"></script>`)
+
+var imageContentTypes = map[string]string{
+	"png": "image/png",
+	"svg": "image/svg+xml",
+}
+
+var findingTypeNames = map[string]string{
+	security.FindingImpossibleTravel: "Impossible travel",
+	security.FindingLoginBurst:       "Login burst",
+	security.FindingManyIps:          "Many IP addresses",
+}
+
+var consumerEmailDomains = []string{
+	"163.com", "aol.com", "gmail.com", "gmx.com", "gmx.de", "gmx.net", "googlemail.com", "hotmail.co.uk", "hotmail.com", "hotmail.fr",
+	"icloud.com", "live.com", "mac.com", "mail.com", "mail.ru", "me.com", "msn.com", "outlook.com", "proton.me", "protonmail.com",
+	"qq.com", "rediffmail.com", "t-online.de", "web.de", "yahoo.co.uk", "yahoo.com", "yahoo.fr", "yandex.ru", "ymail.com", "zoho.com",
+}
+
+var statusPage = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html lang="en">
+	<head>
+		<meta charset="UTF-8">
+		<title>Extraction status</title>
+		<style>
+			body { font-family: sans-serif; margin: 20px; }
+			table { border-collapse: collapse; }
+			th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+			.error { color: #b00020; }
+		</style>
+	</head>
+	<body>
+		<h1>Extraction status</h1>
+		<p><a href="/">Back to charts</a></p>
+		{{if .Schedule}}
+			<p>Schedule: <code>{{.Schedule}}</code>{{if .Running}}, extraction running now{{else if not .NextRun.IsZero}}, next run {{.NextRun.Format "2006-01-02 15:04:05 MST"}}{{end}}</p>
+			<form method="post" action="/admin/extract">{{if .NeedsToken}}<input type="password" name="token" placeholder="Admin token" required> {{end}}<button>Run extraction now</button></form>
+		{{else}}
+			<p>Scheduled extraction is off. Set <code>extract.schedule</code> in config.json to turn it on.</p>
+		{{end}}
+		<p>Serving {{.Users}} users{{if not .ExtractedAt.IsZero}}, extracted {{.ExtractedAt.Format "2006-01-02 15:04:05 MST"}}, loaded {{.LoadedAt.Format "2006-01-02 15:04:05 MST"}}{{end}}.</p>
+		{{if .Runs}}
+			<table>
+				<tr><th>Started</th><th>Duration</th><th>Type</th><th>Users</th><th>New logins</th><th>Result</th></tr>
+				{{range .Runs}}
+					<tr>
+						<td>{{.Start.Format "2006-01-02 15:04:05 MST"}}</td>
+						<td>{{.DurationMs}} ms</td>
+						<td>{{if .Incremental}}Incremental{{else}}Full{{end}}</td>
+						<td>{{.Users}}</td>
+						<td>{{.NewLogins}}</td>
+						<td>{{if .Error}}<span class="error">{{.Error}}</span>{{else}}OK{{end}}</td>
+					</tr>
+				{{end}}
+			</table>
+		{{end}}
+	</body>
+</html>
+`))
+
 func main() {
-	users := getUsersFromFile()
-	chartData := getChartData(users)
+	appConfig, err := config.Load(config.DefaultFile)
+	if err != nil {
+		fmt.Printf("Config error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if err := registerMetricCharts(appConfig.Metrics); err != nil {
+		fmt.Printf("Config error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if err := validateDigestCharts(appConfig.Digest.Charts); err != nil {
+		fmt.Printf("Config error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := runRender(appConfig, os.Args[2:]); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	dashboard := &LiveDashboard{
+		thresholds:  appConfig.Charts,
+		options:     getExtractOptions(appConfig.Extract),
+		refresh:     make(chan struct{}, 1),
+		subscribers: make(map[chan struct{}]bool),
+		alerts:      newAlertManager(appConfig.Alerts),
+		eventIds:    make(map[string]bool),
+		recordIndex: make(map[string]int),
+	}
+	if err := dashboard.reload(); errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("%s not found, serving empty charts until it appears\n", usersFile)
+		dashboard.store(newDashboard([]User{}, appConfig.Charts, time.Time{}))
+	} else if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
 	fmt.Println("Charts created")
+	go dashboard.watch(usersFilePollInterval)
+	go dashboard.refreshOnEvents(webhookRefreshDelay)
+	scheduler, err := newExtractScheduler(appConfig.Extract, dashboard)
+	if err != nil {
+		fmt.Printf("Config error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if scheduler.enabled {
+		go scheduler.run()
+	}
+	digestScheduler, err := newDigestScheduler(appConfig.Digest, dashboard)
+	if err != nil {
+		fmt.Printf("Config error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if digestScheduler.enabled {
+		go digestScheduler.run()
+	}
 	http.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
-		page := getPage(chartData)
+		chartData, isValid := dashboard.current().getChartDataForRequest(writer, request)
+		if !isValid {
+			return
+		}
+		page := getPage(chartData, nil)
 		fmt.Fprint(writer, page)
 	})
+	http.HandleFunc("/api/suspicious-logins", func(writer http.ResponseWriter, request *http.Request) {
+		chartData, isValid := dashboard.current().getChartDataForRequest(writer, request)
+		if !isValid {
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(chartData.SuspiciousUsers)
+	})
+	http.HandleFunc("/api/events", dashboard.streamUpdates)
+	http.HandleFunc("/api/alerts", func(writer http.ResponseWriter, request *http.Request) {
+		states := []alerts.RuleState{}
+		if dashboard.alerts != nil {
+			states = dashboard.alerts.States()
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(states)
+	})
+	http.HandleFunc("/metrics", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", prometheus.ContentType)
+		if err := prometheus.Write(writer, getPrometheusMetrics(dashboard, scheduler, time.Now())); err != nil {
+			fmt.Printf("Writing metrics failed: %s\n", err.Error())
+		}
+	})
+	http.HandleFunc("/api/charts", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(lo.Map(chartRegistry, func(definition ChartDefinition, _ int) ChartOutput {
+			return ChartOutput{Name: definition.Name(), Title: definition.Title(), Hints: definition.Hints()}
+		}))
+	})
+	http.HandleFunc("/api/charts/{name}", func(writer http.ResponseWriter, request *http.Request) {
+		chartData, isValid := dashboard.current().getChartDataForRequest(writer, request)
+		if !isValid {
+			return
+		}
+		chart, exists := chartData.getChart(request.PathValue("name"))
+		if !exists {
+			http.Error(writer, "Unknown chart", http.StatusNotFound)
+			return
+		}
+		if request.URL.Query().Get("format") == "csv" {
+			writer.Header().Set("Content-Type", "text/csv")
+			writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", chart.Name+".csv"))
+			writeChartCsv(writer, chart)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(chart)
+	})
+	http.HandleFunc("/charts/{file}", func(writer http.ResponseWriter, request *http.Request) {
+		name, format, _ := strings.Cut(request.PathValue("file"), ".")
+		contentType, isImage := imageContentTypes[format]
+		if !isImage {
+			http.Error(writer, "Charts are available as .png or .svg", http.StatusNotFound)
+			return
+		}
+		chartData, isValid := dashboard.current().getChartDataForRequest(writer, request)
+		if !isValid {
+			return
+		}
+		chart, exists := chartData.getChart(name)
+		if !exists {
+			http.Error(writer, "Unknown chart", http.StatusNotFound)
+			return
+		}
+		width, height, err := getImageSize(chart, request.URL.Query().Get("width"), request.URL.Query().Get("height"))
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content, err := renderChartImage(chart, format, width, height)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		}
+		writer.Header().Set("Content-Type", contentType)
+		writer.Write(content)
+	})
+	http.HandleFunc("/report.pdf", func(writer http.ResponseWriter, request *http.Request) {
+		var buffer bytes.Buffer
+		if err := writeReport(&buffer, dashboard.current(), request.URL.Query(), appConfig.Digest.WindowDays, time.Now()); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/pdf")
+		writer.Header().Set("Content-Disposition", `inline; filename="report.pdf"`)
+		writer.Write(buffer.Bytes())
+	})
+	http.HandleFunc("POST /admin/reload", requireAdmin(appConfig.Admin.Token, func(writer http.ResponseWriter, request *http.Request) {
+		if err := dashboard.reload(); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		current := dashboard.current()
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(map[string]any{"users": len(current.users), "extractedAt": current.extractedAt, "loadedAt": current.loadedAt})
+	}))
+	http.HandleFunc("/status", func(writer http.ResponseWriter, request *http.Request) {
+		status := scheduler.getStatus()
+		status.NeedsToken = appConfig.Admin.Token != ""
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		statusPage.Execute(writer, status)
+	})
+	http.HandleFunc("/api/status", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(scheduler.getStatus())
+	})
+	http.HandleFunc("POST /admin/extract", requireAdmin(appConfig.Admin.Token, func(writer http.ResponseWriter, request *http.Request) {
+		if !scheduler.enabled {
+			http.Error(writer, "Scheduled extraction is off, set extract.schedule in config.json", http.StatusConflict)
+			return
+		}
+		select {
+		case scheduler.trigger <- struct{}{}:
+			if request.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+				http.Redirect(writer, request, "/status", http.StatusSeeOther)
+				return
+			}
+			writer.WriteHeader(http.StatusAccepted)
+			fmt.Fprintln(writer, "Extraction started")
+		default:
+			http.Error(writer, "An extraction is already queued", http.StatusConflict)
+		}
+	}))
+	http.HandleFunc("/digest/preview", func(writer http.ResponseWriter, request *http.Request) {
+		report, err := getDigestReport(dashboard.current(), appConfig.Digest, time.Now())
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		html, err := digest.RenderHtml(report, digest.DataUrl)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(writer, html)
+	})
+	http.HandleFunc("POST /admin/digest", requireAdmin(appConfig.Admin.Token, func(writer http.ResponseWriter, request *http.Request) {
+		if appConfig.Digest.Smtp == nil {
+			http.Error(writer, "Digest email is off, set digest.smtp in config.json", http.StatusConflict)
+			return
+		}
+		if err := digestScheduler.send(request.Context()); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadGateway)
+			return
+		}
+		fmt.Fprintf(writer, "Digest sent to %s\n", strings.Join(appConfig.Digest.Smtp.To, ", "))
+	}))
+	http.HandleFunc("POST /webhooks/fusionauth", func(writer http.ResponseWriter, request *http.Request) {
+		if appConfig.Webhook.Secret == "" {
+			http.Error(writer, "Webhooks are off, set webhook.secret in config.json", http.StatusNotFound)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxWebhookBodyBytes))
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := webhook.Verify(body, request.Header.Get(webhook.SignatureHeader), []byte(appConfig.Webhook.Secret), time.Now()); err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		event, err := webhook.Parse(body)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		changed, duplicate := dashboard.applyEvent(event)
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(map[string]any{"type": event.Type, "applied": changed, "duplicate": duplicate})
+	})
 	fmt.Println("Server listening at http://0.0.0.0:7777")
 	http.ListenAndServe("0.0.0.0:7777", nil)
 }
 
-func getUsersFromFile() []User {
-	fileContent, _ := os.ReadFile("users.json")
-	var users []User
-	json.Unmarshal(fileContent, &users)
+func requireAdmin(token string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if token == "" && !isLoopback(request.RemoteAddr) {
+			http.Error(writer, "Admin endpoints only accept requests from localhost, set admin.token in config.json to allow others", http.StatusForbidden)
+			return
+		}
+		if token != "" && !hasAdminToken(request, token) {
+			writer.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(writer, "Admin token is missing or wrong", http.StatusUnauthorized)
+			return
+		}
+		handler(writer, request)
+	}
+}
+
+func hasAdminToken(request *http.Request, token string) bool {
+	provided, isBearer := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if !isBearer {
+		provided = request.PostFormValue("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+func isLoopback(remoteAddress string) bool {
+	host, _, err := net.SplitHostPort(remoteAddress)
+	if err != nil {
+		return false
+	}
+	address, err := netip.ParseAddr(host)
+	return err == nil && address.Unmap().IsLoopback()
+}
+
+func (l *LiveDashboard) current() *Dashboard {
+	return l.dashboard.Load()
+}
+
+func (l *LiveDashboard) store(dashboard *Dashboard) {
+	l.dashboard.Store(dashboard)
+	if l.alerts != nil {
+		go l.checkAlerts(dashboard)
+	}
+	l.subscriberMutex.Lock()
+	defer l.subscriberMutex.Unlock()
+	for subscriber := range l.subscribers {
+		select {
+		case subscriber <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (l *LiveDashboard) checkAlerts(dashboard *Dashboard) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertNotifyTimeout)
+	defer cancel()
+	notifications, err := l.alerts.Check(ctx, getAlertUsers(dashboard.users), time.Now())
+	for _, notification := range notifications {
+		fmt.Printf("Alert %s\n", notification.String())
+	}
+	if err != nil {
+		fmt.Printf("Sending alert notifications failed: %s\n", err.Error())
+	}
+}
+
+func getAlertUsers(users []User) []alerts.User {
+	return lo.Map(users, func(user User, _ int) alerts.User {
+		return alerts.User{IsVerified: user.IsVerified, IsBot: user.BotScore.IsBot, RegisteredDate: user.RegisteredDate, Logins: user.LoginDates}
+	})
+}
+
+func newAlertManager(alertsConfig config.AlertsConfig) *alerts.Manager {
+	if len(alertsConfig.Rules) == 0 {
+		return nil
+	}
+	var notifiers []alerts.Notifier
+	if alertsConfig.Smtp != nil {
+		notifiers = append(notifiers, alerts.SmtpNotifier{Options: *alertsConfig.Smtp})
+	}
+	if alertsConfig.WebhookUrl != "" {
+		notifiers = append(notifiers, alerts.WebhookNotifier{Url: alertsConfig.WebhookUrl})
+	}
+	return alerts.NewManager(alertsConfig.Rules, time.Duration(alertsConfig.CooldownMinutes)*time.Minute, notifiers)
+}
+
+func (l *LiveDashboard) subscribe() chan struct{} {
+	subscriber := make(chan struct{}, 1)
+	l.subscriberMutex.Lock()
+	l.subscribers[subscriber] = true
+	l.subscriberMutex.Unlock()
+	return subscriber
+}
+
+func (l *LiveDashboard) unsubscribe(subscriber chan struct{}) {
+	l.subscriberMutex.Lock()
+	delete(l.subscribers, subscriber)
+	l.subscriberMutex.Unlock()
+}
+
+func (l *LiveDashboard) streamUpdates(writer http.ResponseWriter, request *http.Request) {
+	previous, isValid := l.current().getChartDataForRequest(writer, request)
+	if !isValid {
+		return
+	}
+	flusher, canFlush := writer.(http.Flusher)
+	if !canFlush {
+		http.Error(writer, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	subscriber := l.subscribe()
+	defer l.unsubscribe(subscriber)
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprintf(writer, "retry: %d\n\n", eventRetryDelay.Milliseconds())
+	flusher.Flush()
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(writer, ": keep-alive\n\n")
+		case <-subscriber:
+			current, err := l.current().getChartDataForQuery(request.URL.Query())
+			if err != nil {
+				writeEvent(writer, "error", map[string]string{"message": err.Error()})
+				break
+			}
+			writeEvent(writer, "update", getChartDelta(previous, current))
+			previous = current
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(writer io.Writer, name string, value any) {
+	content, _ := json.Marshal(value)
+	fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", name, content)
+}
+
+func getPrometheusMetrics(live *LiveDashboard, scheduler *ExtractScheduler, now time.Time) []prometheus.Metric {
+	d := live.current()
+	users := prometheus.Metric{Name: "fusionauth_users", Help: "Users registered for the application, by email verification.", Type: prometheus.TypeGauge}
+	verifiedCount := lo.CountBy(d.users, func(user User) bool { return user.IsVerified })
+	users.Add(float64(verifiedCount), "verified", "true")
+	users.Add(float64(len(d.users)-verifiedCount), "verified", "false")
+	activeUsers := prometheus.Metric{Name: "fusionauth_active_users", Help: "Users with a login within the window.", Type: prometheus.TypeGauge}
+	activeCounts := make(map[int]int)
+	for _, days := range activeUserWindowDays {
+		activeCounts[days] = lo.CountBy(d.users, func(user User) bool {
+			return len(user.LoginDates) > 0 && now.Sub(user.LoginDates[len(user.LoginDates)-1]) <= time.Duration(days)*24*time.Hour
+		})
+		activeUsers.Add(float64(activeCounts[days]), "window", fmt.Sprintf("%dd", days))
+	}
+	inactiveUsers := prometheus.Metric{Name: "fusionauth_inactive_users", Help: "Users whose last login was at least this many days ago, from charts.abandonmentDays.", Type: prometheus.TypeGauge}
+	for _, days := range d.thresholds.AbandonmentDays {
+		inactiveUsers.Add(float64(lo.CountBy(d.users, func(user User) bool {
+			return len(user.LoginDates) > 0 && int(now.Sub(user.LoginDates[len(user.LoginDates)-1]).Hours()/24) >= days
+		})), "days", strconv.Itoa(days))
+	}
+	friction := prometheus.Metric{Name: "fusionauth_first_login_users", Help: "Users by time from registration to first login, from charts.frictionDays.", Type: prometheus.TypeGauge}
+	frictionChart := calculateFrictionChart(applySplit(d.users, ""), d.thresholds.FrictionDays)
+	for index, label := range frictionChart.Labels {
+		friction.Add(lo.SumBy(frictionChart.Series, func(series ChartSeries) float64 { return series.Data[index] }), "bucket", label)
+	}
+	dauMau := math.NaN()
+	if activeCounts[30] > 0 {
+		dauMau = float64(activeCounts[1]) / float64(activeCounts[30])
+	}
+	result := []prometheus.Metric{
+		users,
+		activeUsers,
+		prometheus.NewGauge("fusionauth_dau_mau_ratio", "Users active in the past day divided by users active in the past 30 days.", dauMau),
+		inactiveUsers,
+		prometheus.NewGauge("fusionauth_users_never_logged_in", "Users who never logged in.", float64(lo.CountBy(d.users, func(user User) bool { return len(user.LoginDates) == 0 }))),
+		friction,
+		prometheus.NewGauge("fusionauth_suspected_bots", "Users with a bot score of at least the bot threshold.", float64(lo.CountBy(d.users, func(user User) bool { return user.BotScore.IsBot }))),
+		prometheus.NewGauge("fusionauth_charts_compute_duration_seconds", "Time taken to compute the default view's charts on the last load.", d.computeDuration.Seconds()),
+		prometheus.NewGauge("fusionauth_data_loaded_timestamp_seconds", "When the data being served was loaded.", float64(d.loadedAt.UnixMilli())/1000),
+	}
+	if !d.extractedAt.IsZero() {
+		result = append(result,
+			prometheus.NewGauge("fusionauth_data_extracted_timestamp_seconds", "When users.json was last written.", float64(d.extractedAt.UnixMilli())/1000),
+			prometheus.NewGauge("fusionauth_data_age_seconds", "Time since users.json was last written.", now.Sub(d.extractedAt).Seconds()),
+		)
+	}
+	webhookEvents := prometheus.Metric{Name: "fusionauth_webhook_events_total", Help: "Webhook events received with a valid signature, by whether they changed any user.", Type: prometheus.TypeCounter}
+	applied := live.webhookEventsApplied.Load()
+	webhookEvents.Add(float64(applied), "applied", "true")
+	webhookEvents.Add(float64(live.webhookEvents.Load()-applied), "applied", "false")
+	result = append(result, webhookEvents)
+	if live.alerts != nil {
+		firing := prometheus.Metric{Name: "fusionauth_alert_firing", Help: "1 if the alert rule is firing, 0 if not.", Type: prometheus.TypeGauge}
+		for _, state := range live.alerts.States() {
+			firing.Add(lo.Ternary(state.Result.Firing, 1.0, 0.0), "rule", state.Result.Rule)
+		}
+		result = append(result, firing)
+	}
+	if scheduler.enabled {
+		status := scheduler.getStatus()
+		runs := prometheus.Metric{Name: "fusionauth_extract_runs_total", Help: "Scheduled extractions, by result.", Type: prometheus.TypeCounter}
+		runs.Add(float64(status.Successful), "result", "success")
+		runs.Add(float64(status.Failed), "result", "error")
+		result = append(result, runs)
+		if len(status.Runs) > 0 {
+			result = append(result,
+				prometheus.NewGauge("fusionauth_extract_last_duration_seconds", "Duration of the last scheduled extraction.", float64(status.Runs[0].DurationMs)/1000),
+				prometheus.NewGauge("fusionauth_extract_last_success", "1 if the last scheduled extraction succeeded, 0 if it failed.", lo.Ternary(status.Runs[0].Error == "", 1.0, 0.0)),
+			)
+		}
+	}
+	return result
+}
+
+func getChartDelta(previous ChartResult, current ChartResult) ChartResult {
+	delta := current
+	delta.Charts = []ChartOutput{}
+	for _, chart := range current.Charts {
+		previousChart, exists := previous.getChart(chart.Name)
+		previousJson, _ := json.Marshal(previousChart.Data)
+		currentJson, _ := json.Marshal(chart.Data)
+		if !exists || string(previousJson) != string(currentJson) {
+			delta.Charts = append(delta.Charts, chart)
+		}
+	}
+	return delta
+}
+
+func (l *LiveDashboard) reload() error {
+	return l.reloadSince(time.Time{})
+}
+
+func (l *LiveDashboard) reloadSince(extractStart time.Time) error {
+	l.reloadMutex.Lock()
+	defer l.reloadMutex.Unlock()
+	info, err := os.Stat(usersFile)
+	if err != nil {
+		return err
+	}
+	records, err := extract.ReadUsers(usersFile)
+	if err != nil {
+		return err
+	}
+	if extractStart.IsZero() {
+		extractStart = info.ModTime()
+	}
+	recordIndex := webhook.IndexById(records)
+	events := lo.Filter(l.events, func(received receivedEvent, _ int) bool { return !received.receivedAt.Before(extractStart) })
+	for _, received := range events {
+		records, _ = webhook.Apply(records, recordIndex, received.event, l.options)
+	}
+	users, err := getUsers(records, l.getGeoIpReader())
+	if err != nil {
+		return err
+	}
+	l.records, l.recordIndex, l.changedIds = records, recordIndex, nil
+	l.events = events
+	l.store(newDashboard(users, l.thresholds, info.ModTime()))
+	return nil
+}
+
+func (l *LiveDashboard) applyEvent(event webhook.Event) (bool, bool) {
+	l.reloadMutex.Lock()
+	if event.Id != "" {
+		if l.eventIds[event.Id] {
+			l.reloadMutex.Unlock()
+			return false, true
+		}
+		l.eventIds[event.Id] = true
+		l.eventIdOrder = append(l.eventIdOrder, event.Id)
+		if len(l.eventIdOrder) > maxRetainedWebhookEvents {
+			delete(l.eventIds, l.eventIdOrder[0])
+			l.eventIdOrder = l.eventIdOrder[1:]
+		}
+	}
+	records, changed := webhook.Apply(l.records, l.recordIndex, event, l.options)
+	l.records = records
+	if changed {
+		l.changedIds = append(l.changedIds, event.User.Id)
+	}
+	l.events = append(l.events, receivedEvent{event: event, receivedAt: time.Now()})
+	if len(l.events) > maxRetainedWebhookEvents {
+		l.events = l.events[1:]
+	}
+	l.reloadMutex.Unlock()
+	l.webhookEvents.Add(1)
+	if changed {
+		l.webhookEventsApplied.Add(1)
+		select {
+		case l.refresh <- struct{}{}:
+		default:
+		}
+	}
+	return changed, false
+}
+
+func (l *LiveDashboard) refreshOnEvents(delay time.Duration) {
+	for range l.refresh {
+		time.Sleep(delay)
+		l.reloadMutex.Lock()
+		if err := l.refreshChangedUsers(); err != nil {
+			fmt.Printf("Applying webhook events failed, still serving the previous data: %s\n", err.Error())
+		}
+		l.reloadMutex.Unlock()
+	}
+}
+
+func (l *LiveDashboard) refreshChangedUsers() error {
+	if len(l.changedIds) == 0 {
+		return nil
+	}
+	changed := make(map[int]User)
+	for _, id := range lo.Uniq(l.changedIds) {
+		index := l.recordIndex[id]
+		user, err := newUser(l.records[index])
+		if err != nil {
+			return err
+		}
+		users := []User{user}
+		addDeduplicatedLoginDates(users)
+		addLocations(users, l.getGeoIpReader())
+		changed[index] = users[0]
+	}
+	l.changedIds = nil
+	l.store(l.current().update(changed))
+	return nil
+}
+
+func (l *LiveDashboard) getGeoIpReader() *geoip.Reader {
+	l.geoIpOnce.Do(func() {
+		reader, err := geoip.Open(geoIpDatabaseFile)
+		if err != nil {
+			fmt.Printf("GeoIP database %s not available, countries will be %s: %s\n", geoIpDatabaseFile, unknownCountry, err.Error())
+		}
+		l.geoIp = reader
+	})
+	return l.geoIp
+}
+
+func (l *LiveDashboard) watch(interval time.Duration) {
+	var failedModTime time.Time
+	for range time.Tick(interval) {
+		info, err := os.Stat(usersFile)
+		if err != nil || info.ModTime().Equal(l.current().extractedAt) || info.ModTime().Equal(failedModTime) {
+			continue
+		}
+		if err := l.reload(); err != nil {
+			failedModTime = info.ModTime()
+			fmt.Printf("Reload of %s failed, still serving the previous data: %s\n", usersFile, err.Error())
+			continue
+		}
+		fmt.Printf("Reloaded %s with %d users\n", usersFile, len(l.current().users))
+	}
+}
+
+func getExtractOptions(extractConfig config.ExtractConfig) extract.Options {
+	return extract.Options{
+		ApplicationId: extractConfig.ApplicationId,
+		FieldMappings: lo.Map(extractConfig.FieldMappings, func(mapping config.FieldMapping, _ int) extract.FieldMapping {
+			return extract.FieldMapping{Name: mapping.Name, Path: mapping.Parsed}
+		}),
+	}
+}
+
+func newExtractScheduler(extractConfig config.ExtractConfig, dashboard *LiveDashboard) (*ExtractScheduler, error) {
+	scheduler := &ExtractScheduler{
+		client:    fusionauth.NewClient(extractConfig.FusionAuthUrl, extractConfig.ApiKey),
+		options:   getExtractOptions(extractConfig),
+		dashboard: dashboard,
+		trigger:   make(chan struct{}, 1),
+		status:    ExtractStatus{Schedule: extractConfig.Schedule, Runs: []ExtractRun{}},
+	}
+	if extractConfig.Schedule != "" {
+		parsed, err := schedule.Parse(extractConfig.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("extract: %w", err)
+		}
+		scheduler.schedule = parsed
+		scheduler.enabled = true
+	}
+	return scheduler, nil
+}
+
+func (s *ExtractScheduler) run() {
+	fmt.Printf("Extracting from FusionAuth on schedule %q\n", s.schedule.String())
+	s.extract()
+	for {
+		next := s.schedule.Next(time.Now())
+		s.setNextRun(next)
+		var timer <-chan time.Time
+		if next.IsZero() {
+			fmt.Printf("Schedule %q has no next run, extracting only when requested\n", s.schedule.String())
+		} else {
+			timer = time.After(time.Until(next))
+		}
+		select {
+		case <-timer:
+		case <-s.trigger:
+		}
+		s.extract()
+	}
+}
+
+func (s *ExtractScheduler) extract() {
+	s.statusMutex.Lock()
+	s.status.Running = true
+	s.status.NextRun = time.Time{}
+	s.statusMutex.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), extractTimeout)
+	defer cancel()
+	result, err := extract.Run(ctx, s.client, s.options, s.previousUsers, s.previousStart)
+	if err == nil {
+		err = extract.WriteJson(usersFile, result.Users)
+	}
+	if err == nil {
+		err = s.dashboard.reloadSince(result.Start)
+	}
+	run := ExtractRun{Start: result.Start, DurationMs: time.Since(result.Start).Milliseconds(), Users: len(result.Users), NewLogins: result.NewLogins, Incremental: result.Incremental}
+	if err != nil {
+		run.Error = err.Error()
+		fmt.Printf("Extraction failed, still serving the previous data: %s\n", run.Error)
+	} else {
+		s.previousUsers = result.Users
+		s.previousStart = result.Start
+		fmt.Printf("Extracted %d users with %d new logins in %dms\n", run.Users, run.NewLogins, run.DurationMs)
+	}
+	s.statusMutex.Lock()
+	s.status.Running = false
+	if run.Error == "" {
+		s.status.Successful++
+	} else {
+		s.status.Failed++
+	}
+	s.status.Runs = append([]ExtractRun{run}, s.status.Runs[:min(len(s.status.Runs), maxExtractRuns-1)]...)
+	s.statusMutex.Unlock()
+}
+
+func (s *ExtractScheduler) setNextRun(next time.Time) {
+	s.statusMutex.Lock()
+	s.status.NextRun = next
+	s.statusMutex.Unlock()
+}
+
+func (s *ExtractScheduler) getStatus() ExtractStatus {
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+	status := s.status
+	status.Runs = slices.Clone(s.status.Runs)
+	current := s.dashboard.current()
+	status.Users = len(current.users)
+	status.ExtractedAt = current.extractedAt
+	status.LoadedAt = current.loadedAt
+	return status
+}
+
+func newDigestScheduler(digestConfig config.DigestConfig, dashboard *LiveDashboard) (*DigestScheduler, error) {
+	scheduler := &DigestScheduler{config: digestConfig, dashboard: dashboard}
+	if digestConfig.Schedule != "" {
+		parsed, err := schedule.Parse(digestConfig.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("digest: %w", err)
+		}
+		scheduler.schedule = parsed
+		scheduler.enabled = true
+	}
+	return scheduler, nil
+}
+
+func (s *DigestScheduler) run() {
+	fmt.Printf("Sending the digest on schedule %q\n", s.schedule.String())
+	for {
+		next := s.schedule.Next(time.Now())
+		if next.IsZero() {
+			fmt.Printf("Schedule %q has no next run, stopped sending the digest\n", s.schedule.String())
+			return
+		}
+		time.Sleep(time.Until(next))
+		ctx, cancel := context.WithTimeout(context.Background(), digestSendTimeout)
+		err := s.send(ctx)
+		cancel()
+		if err != nil {
+			fmt.Printf("Sending the digest failed: %s\n", err.Error())
+		}
+	}
+}
+
+func (s *DigestScheduler) send(ctx context.Context) error {
+	report, err := getDigestReport(s.dashboard.current(), s.config, time.Now())
+	if err != nil {
+		return err
+	}
+	message, err := digest.NewMessage(report)
+	if err != nil {
+		return err
+	}
+	if err := mail.Send(ctx, *s.config.Smtp, message); err != nil {
+		return err
+	}
+	fmt.Printf("Sent the digest to %s\n", strings.Join(s.config.Smtp.To, ", "))
+	return nil
+}
+
+func getDigestReport(dashboard *Dashboard, digestConfig config.DigestConfig, now time.Time) (digest.Report, error) {
+	chartData, err := dashboard.getChartDataForQuery(url.Values{})
+	if err != nil {
+		return digest.Report{}, err
+	}
+	report := digest.Report{
+		Title:        fmt.Sprintf("%s — %s", digestConfig.Subject, now.Format("2006-01-02")),
+		GeneratedAt:  now,
+		ExtractedAt:  dashboard.extractedAt,
+		WindowDays:   digestConfig.WindowDays,
+		DashboardUrl: digestConfig.DashboardUrl,
+		Kpis:         digest.GetKpis(getAlertUsers(dashboard.users), digestConfig.WindowDays, now),
+	}
+	for _, name := range digestConfig.Charts {
+		chart, _ := chartData.getChart(name)
+		height := getDefaultImageHeight(chart, digestImageWidth)
+		content, err := renderChartImage(chart, "png", digestImageWidth, height)
+		if err != nil {
+			return digest.Report{}, err
+		}
+		report.Charts = append(report.Charts, digest.Chart{Name: chart.Name, Title: chart.Title, Png: content, Width: digestImageWidth, Height: height})
+	}
+	return report, nil
+}
+
+func getChartImage(chart ChartOutput) (chartimage.Drawing, error) {
+	switch data := chart.Data.(type) {
+	case ChartData:
+		return chartimage.Chart{
+			Title:      chart.Title,
+			XAxisTitle: chart.Hints.XAxisTitle,
+			Unit:       data.Unit,
+			Labels:     data.Labels,
+			Series: lo.Map(data.Series, func(series ChartSeries, _ int) chartimage.Series {
+				return chartimage.Series{Name: series.Name, Data: series.Data}
+			}),
+			Stacked: data.Stacked,
+		}, nil
+	case RetentionChartData:
+		return chartimage.Heatmap{
+			Title:      chart.Title,
+			XAxisTitle: "Months since registration",
+			YAxisTitle: chart.Hints.CohortName,
+			Columns:    data.YLabels,
+			Rows:       data.XLabels,
+			Cells: lo.Map(data.MatrixData, func(point MatrixPoint, _ int) chartimage.Cell {
+				return chartimage.Cell{Column: point.Y, Row: point.X, Value: point.V}
+			}),
+		}, nil
+	}
+	return nil, fmt.Errorf("chart %q is a %s and can't be drawn as an image", chart.Name, chart.Hints.Kind)
+}
+
+func renderChartImage(chart ChartOutput, format string, width int, height int) ([]byte, error) {
+	image, err := getChartImage(chart)
+	if err != nil {
+		return nil, err
+	}
+	if format == "svg" {
+		return chartimage.RenderSvg(image, width, height)
+	}
+	return chartimage.RenderPng(image, width, height)
+}
+
+func getDefaultImageHeight(chart ChartOutput, width int) int {
+	if data, isHeatmap := chart.Data.(RetentionChartData); isHeatmap {
+		return min(max(width/2, len(data.XLabels)*heatmapRowHeight+heatmapMargin), maxImageSize)
+	}
+	return width / 2
+}
+
+func getImageSize(chart ChartOutput, widthText string, heightText string) (int, int, error) {
+	width := defaultImageWidth
+	if widthText != "" {
+		value, err := strconv.Atoi(widthText)
+		if err != nil || value < minImageSize || value > maxImageSize {
+			return 0, 0, fmt.Errorf("width must be a whole number from %d to %d", minImageSize, maxImageSize)
+		}
+		width = value
+	}
+	height := getDefaultImageHeight(chart, width)
+	if heightText != "" {
+		value, err := strconv.Atoi(heightText)
+		if err != nil || value < minImageSize || value > maxImageSize {
+			return 0, 0, fmt.Errorf("height must be a whole number from %d to %d", minImageSize, maxImageSize)
+		}
+		height = value
+	}
+	return width, height, nil
+}
+
+func runRender(appConfig config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(renderUsage)
+	}
+	switch args[0] {
+	case "chart":
+		return renderChart(appConfig, args)
+	case "report":
+		return renderReport(appConfig, args)
+	case "static":
+		return renderStatic(appConfig, args)
+	}
+	return errors.New(renderUsage)
+}
+
+func renderReport(appConfig config.Config, args []string) error {
+	flags := flag.NewFlagSet("render report", flag.ContinueOnError)
+	outputFile := flags.String("output", "report.pdf", "file to write the report to")
+	filters := flags.String("query", "", "filters in the page's query string format, for example country=Brazil&excludeBots=true")
+	if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 0 {
+		return errors.New(renderUsage)
+	}
+	query, err := url.ParseQuery(*filters)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	dashboard, err := loadDashboard(appConfig)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(*outputFile)
+	if err != nil {
+		return err
+	}
+	err = writeReport(file, dashboard, query, appConfig.Digest.WindowDays, time.Now())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", *outputFile)
+	return nil
+}
+
+func writeReport(writer io.Writer, dashboard *Dashboard, query url.Values, windowDays int, now time.Time) error {
+	chartData, err := dashboard.getChartDataForQuery(query)
+	if err != nil {
+		return err
+	}
+	readme, _ := os.ReadFile(readmeFile)
+	descriptions := report.ParseChartGuide(string(readme))
+	document := report.Report{
+		Title:       "User charts report",
+		GeneratedAt: now,
+		ExtractedAt: dashboard.extractedAt,
+		Filters:     getFilterDescription(chartData),
+		WindowDays:  windowDays,
+		Kpis:        digest.GetKpis(getAlertUsers(dashboard.users), windowDays, now),
+	}
+	for _, chart := range chartData.Charts {
+		section := report.Section{Title: chart.Title, Description: descriptions[chart.Name]}
+		if table, isTable := chart.Data.(TableData); isTable {
+			rows := table.Rows[:min(len(table.Rows), maxReportTableRows)]
+			section.Table = &report.Table{Columns: table.Columns, Rows: rows, TotalRows: len(table.Rows)}
+		} else {
+			drawing, err := getChartImage(chart)
+			if err != nil {
+				return err
+			}
+			section.Image, err = chartimage.RenderImage(drawing, reportImageWidth, getDefaultImageHeight(chart, reportImageWidth))
+			if err != nil {
+				return err
+			}
+		}
+		document.Sections = append(document.Sections, section)
+	}
+	return report.Write(writer, document)
+}
+
+func getFilterDescription(chartData ChartResult) string {
+	var filters []string
+	if chartData.SelectedCountry != "" {
+		filters = append(filters, "country "+chartData.SelectedCountry)
+	}
+	if chartData.SelectedDomain != "" {
+		filters = append(filters, "email domain "+chartData.SelectedDomain)
+	}
+	if chartData.ExcludeBots {
+		filters = append(filters, "excluding suspected bots")
+	}
+	if chartData.SplitBy != splitByVerified {
+		filters = append(filters, "split by "+chartData.SplitBy)
+	}
+	for _, threshold := range []struct {
+		name    string
+		value   []int
+		initial []int
+	}{
+		{"cohort logins", chartData.Thresholds.ActivityCohortLogins, chartData.DefaultThresholds.ActivityCohortLogins},
+		{"friction days", chartData.Thresholds.FrictionDays, chartData.DefaultThresholds.FrictionDays},
+		{"abandonment days", chartData.Thresholds.AbandonmentDays, chartData.DefaultThresholds.AbandonmentDays},
+	} {
+		if !slices.Equal(threshold.value, threshold.initial) {
+			filters = append(filters, threshold.name+" "+formatThresholds(threshold.value))
+		}
+	}
+	return strings.Join(filters, ", ")
+}
+
+func renderChart(appConfig config.Config, args []string) error {
+	flags := flag.NewFlagSet("render chart", flag.ContinueOnError)
+	outputFile := flags.String("output", "", "file to write the image to, <name>.png or <name>.svg by default")
+	width := flags.String("width", "", "image width in pixels")
+	height := flags.String("height", "", "image height in pixels")
+	filters := flags.String("query", "", "filters in the page's query string format, for example country=Brazil&excludeBots=true")
+	arguments, file := args[1:], ""
+	if len(arguments) > 0 && !strings.HasPrefix(arguments[0], "-") {
+		file, arguments = arguments[0], arguments[1:]
+	}
+	if err := flags.Parse(arguments); err != nil {
+		return errors.New(renderUsage)
+	}
+	if file == "" && flags.NArg() == 1 {
+		file = flags.Arg(0)
+	} else if flags.NArg() != 0 {
+		return errors.New(renderUsage)
+	}
+	name, format, _ := strings.Cut(file, ".")
+	if _, isImage := imageContentTypes[format]; !isImage {
+		return errors.New(renderUsage)
+	}
+	query, err := url.ParseQuery(*filters)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	dashboard, err := loadDashboard(appConfig)
+	if err != nil {
+		return err
+	}
+	chartData, err := dashboard.getChartDataForQuery(query)
+	if err != nil {
+		return err
+	}
+	chart, exists := chartData.getChart(name)
+	if !exists {
+		return fmt.Errorf("unknown chart %q", name)
+	}
+	imageWidth, imageHeight, err := getImageSize(chart, *width, *height)
+	if err != nil {
+		return err
+	}
+	content, err := renderChartImage(chart, format, imageWidth, imageHeight)
+	if err != nil {
+		return err
+	}
+	if *outputFile == "" {
+		*outputFile = file
+	}
+	if err := os.WriteFile(*outputFile, content, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", *outputFile)
+	return nil
+}
+
+func renderStatic(appConfig config.Config, args []string) error {
+	flags := flag.NewFlagSet("render static", flag.ContinueOnError)
+	outputDir := flags.String("output", "site", "directory to write the site to")
+	if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 0 {
+		return errors.New(renderUsage)
+	}
+	dashboard, err := loadDashboard(appConfig)
+	if err != nil {
+		return err
+	}
+	segments := append([]config.StaticSegment{config.DefaultStaticSegment()}, appConfig.Static.Segments...)
+	site := StaticSite{ExportedAt: time.Now()}
+	views := make([]ChartResult, len(segments))
+	for index, segment := range segments {
+		query, _ := url.ParseQuery(segment.Query)
+		views[index], err = dashboard.getChartDataForQuery(query)
+		if err != nil {
+			return fmt.Errorf("segment %q: %w", segment.Name, err)
+		}
+		site.Segments = append(site.Segments, StaticPage{Name: segment.Name, Title: segment.Title, File: lo.Ternary(index == 0, "index.html", segment.Name+".html")})
+	}
+	if err := os.MkdirAll(filepath.Join(*outputDir, staticAssetsDir), 0755); err != nil {
+		return err
+	}
+	page, err := os.ReadFile(pageFile)
+	if err != nil {
+		return err
+	}
+	scripts, err := vendorScripts(string(page), appConfig.Static.AssetsDir, filepath.Join(*outputDir, staticAssetsDir))
+	if err != nil {
+		return err
+	}
+	for index, chartData := range views {
+		site.Segment = segments[index].Name
+		if err := os.WriteFile(filepath.Join(*outputDir, site.Segments[index].File), []byte(scripts.Replace(getPage(chartData, &site))), 0644); err != nil {
+			return err
+		}
+		chartsDir := filepath.Join(*outputDir, staticChartsDir, segments[index].Name)
+		if err := os.MkdirAll(chartsDir, 0755); err != nil {
+			return err
+		}
+		for _, chart := range chartData.Charts {
+			if err := writeFile(filepath.Join(chartsDir, chart.Name+".json"), func(writer io.Writer) error { return json.NewEncoder(writer).Encode(chart) }); err != nil {
+				return err
+			}
+			if err := writeFile(filepath.Join(chartsDir, chart.Name+".csv"), func(writer io.Writer) error { return writeChartCsv(writer, chart) }); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Printf("Wrote %d pages to %s\n", len(views), *outputDir)
+	return nil
+}
+
+func vendorScripts(page string, cacheDir string, assetsDir string) (*strings.Replacer, error) {
+	var replacements []string
+	for _, match := range remoteScriptPattern.FindAllStringSubmatch(page, -1) {
+		file := path.Base(match[1])
+		if !strings.HasSuffix(file, ".js") {
+			file += ".js"
+		}
+		content, err := getVendoredScript(match[1], filepath.Join(cacheDir, file))
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(assetsDir, file), content, 0644); err != nil {
+			return nil, err
+		}
+		replacements = append(replacements, match[1], staticAssetsDir+"/"+file)
+	}
+	return strings.NewReplacer(replacements...), nil
+}
+
+func getVendoredScript(scriptUrl string, cacheFile string) ([]byte, error) {
+	content, err := os.ReadFile(cacheFile)
+	if !errors.Is(err, os.ErrNotExist) {
+		return content, err
+	}
+	client := http.Client{Timeout: scriptDownloadTimeout}
+	response, err := client.Get(scriptUrl)
+	if err != nil {
+		return nil, fmt.Errorf("%w, download it to %s instead", err, cacheFile)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s, download it to %s instead", scriptUrl, response.Status, cacheFile)
+	}
+	content, err = io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cacheFile, content, 0644); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Downloaded %s to %s\n", scriptUrl, cacheFile)
+	return content, nil
+}
+
+func writeFile(name string, write func(writer io.Writer) error) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func loadDashboard(appConfig config.Config) (*Dashboard, error) {
+	live := &LiveDashboard{
+		thresholds:  appConfig.Charts,
+		options:     getExtractOptions(appConfig.Extract),
+		subscribers: make(map[chan struct{}]bool),
+	}
+	if err := live.reload(); err != nil {
+		return nil, err
+	}
+	return live.current(), nil
+}
+
+func newDashboard(users []User, thresholds config.ChartsConfig, extractedAt time.Time) *Dashboard {
+	computeStart := time.Now()
+	defaultView := getChartData(applySplit(users, ""), thresholds)
+	return &Dashboard{
+		extractedAt:     extractedAt,
+		loadedAt:        time.Now(),
+		computeDuration: time.Since(computeStart),
+		users:           users,
+		countries:       getCountries(users),
+		domains:         getDomainsByUserCount(users),
+		splitOptions:    getSplitOptions(users),
+		thresholds:      thresholds,
+		chartDataByView: map[ViewFilter]*cachedView{{}: {thresholds: thresholds, chartData: func() ChartResult { return defaultView }}},
+	}
+}
+
+func (d *Dashboard) update(changed map[int]User) *Dashboard {
+	computeStart := time.Now()
+	users := slices.Clone(d.users)
+	for index, user := range changed {
+		if index >= len(users) {
+			users = append(users, make([]User, index+1-len(users))...)
+		}
+		users[index] = user
+	}
+	rescoreAll := len(users) != len(d.users) || lo.SomeBy(lo.Keys(changed), func(index int) bool {
+		return users[index].Email != d.users[index].Email || !users[index].RegisteredDate.Equal(d.users[index].RegisteredDate)
+	})
+	if rescoreAll {
+		addBotScores(users)
+		for index := range d.users {
+			if !slices.Equal(users[index].BotScore.Reasons, d.users[index].BotScore.Reasons) {
+				changed[index] = users[index]
+			}
+		}
+	} else {
+		for index := range changed {
+			users[index].BotScore = security.RescoreBot(d.users[index].BotScore, getBotAccount(users[index]), security.DefaultBotOptions())
+		}
+	}
+	affected := []User{}
+	listsChanged := rescoreAll
+	for index := range changed {
+		addEmailDomains(users[index : index+1])
+		affected = append(affected, users[index])
+		if index < len(d.users) {
+			previous := d.users[index]
+			affected = append(affected, previous)
+			listsChanged = listsChanged || previous.Country != users[index].Country || previous.EmailDomain != users[index].EmailDomain || !slices.Equal(slices.Sorted(maps.Keys(previous.Attributes)), slices.Sorted(maps.Keys(users[index].Attributes)))
+		}
+	}
+	dashboard := &Dashboard{
+		extractedAt:     d.extractedAt,
+		users:           users,
+		countries:       d.countries,
+		domains:         d.domains,
+		splitOptions:    d.splitOptions,
+		thresholds:      d.thresholds,
+		chartDataByView: map[ViewFilter]*cachedView{},
+	}
+	if listsChanged {
+		dashboard.countries, dashboard.domains, dashboard.splitOptions = getCountries(users), getDomainsByUserCount(users), getSplitOptions(users)
+	}
+	d.mutex.Lock()
+	for filter, view := range d.chartDataByView {
+		if !lo.SomeBy(affected, func(user User) bool { return matchesFilter(user, filter) }) {
+			dashboard.chartDataByView[filter] = view
+		} else if filter != (ViewFilter{}) {
+			dashboard.chartDataByView[filter] = dashboard.newView(filter, view.thresholds)
+		}
+	}
+	d.mutex.Unlock()
+	if _, exists := dashboard.chartDataByView[ViewFilter{}]; !exists {
+		defaultView := getChartData(applySplit(users, ""), d.thresholds)
+		dashboard.chartDataByView[ViewFilter{}] = &cachedView{thresholds: d.thresholds, chartData: func() ChartResult { return defaultView }}
+	}
+	dashboard.loadedAt = time.Now()
+	dashboard.computeDuration = time.Since(computeStart)
+	return dashboard
+}
+
+func (d *Dashboard) newView(filter ViewFilter, thresholds config.ChartsConfig) *cachedView {
+	return &cachedView{thresholds: thresholds, chartData: sync.OnceValue(func() ChartResult {
+		return getChartData(applySplit(filterUsers(d.users, filter), filter.SplitBy), thresholds)
+	})}
+}
+
+func (d *Dashboard) getChartDataForRequest(writer http.ResponseWriter, request *http.Request) (ChartResult, bool) {
+	chartData, err := d.getChartDataForQuery(request.URL.Query())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return ChartResult{}, false
+	}
+	return chartData, true
+}
+
+func (d *Dashboard) getChartDataForQuery(query url.Values) (ChartResult, error) {
+	filter := ViewFilter{Country: query.Get("country"), Domain: strings.ToLower(query.Get("domain")), ExcludeBots: query.Get("excludeBots") == "true", SplitBy: query.Get("splitBy")}
+	if filter.SplitBy == splitByVerified {
+		filter.SplitBy = ""
+	}
+	if filter.Country != "" && !lo.Contains(d.countries, filter.Country) {
+		return ChartResult{}, errors.New("Unknown country")
+	}
+	if filter.Domain != "" && !lo.Contains(d.domains, filter.Domain) {
+		return ChartResult{}, errors.New("Unknown email domain")
+	}
+	if filter.SplitBy != "" && !lo.ContainsBy(d.splitOptions, func(option SplitOption) bool { return option.Key == filter.SplitBy }) {
+		return ChartResult{}, errors.New("Unknown split")
+	}
+	thresholds := d.thresholds
+	for _, parameter := range []struct {
+		name   string
+		target *[]int
+		key    *string
+	}{
+		{"cohortLogins", &thresholds.ActivityCohortLogins, &filter.CohortLogins},
+		{"frictionDays", &thresholds.FrictionDays, &filter.FrictionDays},
+		{"abandonmentDays", &thresholds.AbandonmentDays, &filter.AbandonmentDays},
+	} {
+		if query.Get(parameter.name) == "" {
+			continue
+		}
+		values, err := config.ParseThresholds(query.Get(parameter.name))
+		if err != nil {
+			return ChartResult{}, fmt.Errorf("%s: %w", parameter.name, err)
+		}
+		if !slices.Equal(values, *parameter.target) {
+			*parameter.target = values
+			*parameter.key = formatThresholds(values)
+		}
+	}
+	d.mutex.Lock()
+	view, exists := d.chartDataByView[filter]
+	if !exists {
+		if len(d.chartDataByView) >= maxCachedViews {
+			d.chartDataByView = map[ViewFilter]*cachedView{{}: d.chartDataByView[ViewFilter{}]}
+		}
+		view = d.newView(filter, thresholds)
+		d.chartDataByView[filter] = view
+	}
+	d.mutex.Unlock()
+	chartData := view.chartData()
+	chartData.Countries = d.countries
+	chartData.SelectedCountry = filter.Country
+	chartData.Domains = d.domains[:min(len(d.domains), maxDomainFilterOptions)]
+	chartData.SelectedDomain = filter.Domain
+	chartData.ExcludeBots = filter.ExcludeBots
+	chartData.SplitOptions = d.splitOptions
+	chartData.Thresholds = thresholds
+	chartData.ExtractedAt = d.extractedAt
+	chartData.LoadedAt = d.loadedAt
+	chartData.DefaultThresholds = d.thresholds
+	chartData.SplitBy = lo.Ternary(filter.SplitBy == "", splitByVerified, filter.SplitBy)
+	return chartData, nil
+}
+
+func getUsers(records []extract.User, reader *geoip.Reader) ([]User, error) {
+	users := make([]User, len(records))
+	for userIndex, record := range records {
+		user, err := newUser(record)
+		if err != nil {
+			return nil, err
+		}
+		users[userIndex] = user
+	}
+	addDeduplicatedLoginDates(users)
+	addLocations(users, reader)
+	addBotScores(users)
+	addEmailDomains(users)
+	return users, nil
+}
+
+func newUser(record extract.User) (User, error) {
+	user := User{Id: record.Id, Email: record.Email, IsVerified: record.IsVerified, Attributes: record.Attributes}
+	user.RegisteredDateRaw, user.LoginDatesRaw = record.RegisteredDate, slices.Clone(record.LoginDates)
+	for _, login := range record.Logins {
+		user.LoginRecords = append(user.LoginRecords, LoginRecord{Instant: login.Instant, IpAddress: login.IpAddress, ApplicationId: login.ApplicationId, ApplicationName: login.ApplicationName, IdentityProvider: login.IdentityProvider})
+	}
+	user.LoginDatesUniqueMonthly = []time.Time{}
+	user.LoginDatesUniqueYearly = []time.Time{}
+	registrationString := fmt.Sprintf("%d", user.RegisteredDateRaw)
+	registrationError := len(registrationString) != 13
+	user.RegisteredDate = time.UnixMilli(user.RegisteredDateRaw)
+	for _, timestamp := range user.LoginDatesRaw {
+		timestampString := fmt.Sprintf("%d", timestamp)
+		if registrationError || len(timestampString) != 13 {
+			return User{}, fmt.Errorf("date error: FusionAuth returned timestamp that doesn't have 13 digits: user %s (%s), registered %s, login %d (%s)", user.Id, user.Email, user.RegisteredDate, timestamp, time.UnixMilli(timestamp))
+		}
+		user.LoginDates = append(user.LoginDates, time.UnixMilli(timestamp))
+	}
+	if len(user.LoginRecords) == 0 {
+		for _, timestamp := range user.LoginDatesRaw {
+			user.LoginRecords = append(user.LoginRecords, LoginRecord{Instant: timestamp})
+		}
+	}
+	for recordIndex := range user.LoginRecords {
+		user.LoginRecords[recordIndex].Date = time.UnixMilli(user.LoginRecords[recordIndex].Instant)
+	}
+	return user, nil
+}
+
+func addLocations(users []User, reader *geoip.Reader) {
+	locations := make(map[string]geoip.Location)
 	for userIndex := range users {
 		user := &users[userIndex]
-		user.LoginDatesUniqueMonthly = []time.Time{}
-		user.LoginDatesUniqueYearly = []time.Time{}
-		registrationString := fmt.Sprintf("%d", user.RegisteredDateRaw)
-		registrationError := len(registrationString) != 13
-		user.RegisteredDate = time.UnixMilli(user.RegisteredDateRaw)
-		for _, timestamp := range user.LoginDatesRaw {
-			timestampString := fmt.Sprintf("%d", timestamp)
-			if registrationError || len(timestampString) != 13 {
-				fmt.Println(" ")
-				fmt.Println("Date error: FusionAuth returned timestamp that doesn't have 13 digits:")
-				fmt.Println(user.Id)
-				fmt.Println(user.Email)
-				fmt.Println(user.RegisteredDate)
-				fmt.Println(timestamp)
-				fmt.Println(time.UnixMilli(timestamp))
-				os.Exit(1)
+		user.Country, user.RegistrationCountry = unknownCountry, unknownCountry
+		for recordIndex := range user.LoginRecords {
+			record := &user.LoginRecords[recordIndex]
+			record.Country = unknownCountry
+			if reader == nil || record.IpAddress == "" {
+				continue
+			}
+			location, exists := locations[record.IpAddress]
+			if !exists {
+				location, _ = reader.Lookup(record.IpAddress)
+				locations[record.IpAddress] = location
 			}
-			user.LoginDates = append(user.LoginDates, time.UnixMilli(timestamp))
+			if location.CountryName != "" {
+				record.Country, record.Region = location.CountryName, location.RegionName
+			}
+			record.Latitude, record.Longitude, record.AccuracyRadiusKm, record.HasCoordinates = location.Latitude, location.Longitude, location.AccuracyRadiusKm, location.HasCoordinates
+		}
+		if len(user.LoginRecords) > 0 {
+			sort.Slice(user.LoginRecords, func(i, j int) bool { return user.LoginRecords[i].Instant < user.LoginRecords[j].Instant })
+			user.RegistrationCountry = user.LoginRecords[0].Country
+			user.Country = user.LoginRecords[len(user.LoginRecords)-1].Country
 		}
 	}
-	addDeduplicatedLoginDates(users)
-	return users
+}
+
+func getCountries(users []User) []string {
+	countries := lo.Uniq(lo.Map(users, func(user User, _ int) string { return user.Country }))
+	sort.Strings(countries)
+	return countries
+}
+
+func addBotScores(users []User) {
+	accounts := lo.Map(users, func(user User, _ int) security.Account { return getBotAccount(user) })
+	scores := security.ScoreBots(accounts, security.DefaultBotOptions())
+	for userIndex := range users {
+		users[userIndex].BotScore = scores[userIndex]
+	}
+}
+
+func getBotAccount(user User) security.Account {
+	return security.Account{Id: user.Id, Email: user.Email, IsVerified: user.IsVerified, RegisteredDate: user.RegisteredDate, LoginCount: len(user.LoginDates)}
+}
+
+func addEmailDomains(users []User) {
+	for userIndex := range users {
+		user := &users[userIndex]
+		user.EmailDomain = strings.ToLower(user.Email[strings.LastIndex(user.Email, "@")+1:])
+		user.IsConsumerDomain = lo.Contains(consumerEmailDomains, user.EmailDomain) || lo.Contains(user.BotScore.Reasons, security.BotReasonDisposableDomain)
+	}
+}
+
+func getDomainsByUserCount(users []User) []string {
+	counts := lo.CountValuesBy(users, func(user User) string { return user.EmailDomain })
+	domains := lo.Keys(counts)
+	sort.Slice(domains, func(i, j int) bool {
+		if counts[domains[i]] != counts[domains[j]] {
+			return counts[domains[i]] > counts[domains[j]]
+		}
+		return domains[i] < domains[j]
+	})
+	return domains
+}
+
+func filterUsers(users []User, filter ViewFilter) []User {
+	if filter.Country == "" && filter.Domain == "" && !filter.ExcludeBots {
+		return users
+	}
+	return lo.Filter(users, func(user User, _ int) bool { return matchesFilter(user, filter) })
+}
+
+func matchesFilter(user User, filter ViewFilter) bool {
+	return (filter.Country == "" || user.Country == filter.Country) && (filter.Domain == "" || user.EmailDomain == filter.Domain) && (!filter.ExcludeBots || !user.BotScore.IsBot)
+}
+
+func getSplitOptions(users []User) []SplitOption {
+	options := []SplitOption{{Key: splitByVerified, Name: "Verified"}, {Key: splitByCountry, Name: "Country"}, {Key: splitByDomainType, Name: "Email domain type"}}
+	attributeNames := lo.Uniq(lo.FlatMap(users, func(user User, _ int) []string { return lo.Keys(user.Attributes) }))
+	sort.Strings(attributeNames)
+	for _, name := range attributeNames {
+		options = append(options, SplitOption{Key: splitByAttributePrefix + name, Name: name})
+	}
+	return options
+}
+
+func applySplit(users []User, splitBy string) []User {
+	return lo.Map(users, func(user User, _ int) User {
+		switch {
+		case splitBy == splitByCountry:
+			user.Series = user.Country
+		case splitBy == splitByDomainType:
+			user.Series = lo.Ternary(user.IsConsumerDomain, "Consumer", "Corporate")
+		case strings.HasPrefix(splitBy, splitByAttributePrefix):
+			user.Series = noAttributeValue
+			if value, exists := user.Attributes[strings.TrimPrefix(splitBy, splitByAttributePrefix)]; exists && value != "" {
+				user.Series = value
+			}
+		default:
+			user.Series = lo.Ternary(user.IsVerified, "Verified", "Unverified")
+		}
+		return user
+	})
 }
 
 func addDeduplicatedLoginDates(users []User) {
@@ -86,63 +1616,215 @@ func addDeduplicatedLoginDates(users []User) {
 	}
 }
 
-func getPage(chartData ChartResult) string {
-	htmlBytes, _ := os.ReadFile("5page.html")
+func getPage(chartData ChartResult, site *StaticSite) string {
+	htmlBytes, _ := os.ReadFile(pageFile)
 	html := string(htmlBytes)
 	chartJson, _ := json.Marshal(chartData)
+	siteJson, _ := json.Marshal(site)
+	html = strings.Replace(html, "{{STATICSITE}}", string(siteJson), 1)
 	return strings.Replace(html, "{{CHARTDATA}}", string(chartJson), 1)
 }
 
-func getChartData(users []User) ChartResult {
+func getChartData(users []User, thresholds config.ChartsConfig) ChartResult {
 	now := time.Now()
 	thisYear := now.Year()
-	minYear := getMinYear(users, thisYear)
-	maxYear := getMaxYear(users, thisYear)
 	startYear, startMonth := getMinYearAndMonth(users, thisYear)
-	result := ChartResult{
-		TotalUsersPerYearChart:        ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}},
-		TotalUsersPerMonthChart:       ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}},
-		NewUsersPerYearChart:          ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}},
-		NewUsersPerMonthChart:         ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}},
-		UserAgeChart:                  ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}},
-		LoginsPerYearChart:            SimpleChartData{Labels: []string{}, Data: []int{}},
-		LoginsPerMonthChart:           SimpleChartData{Labels: []string{}, Data: []int{}},
-		PercentLoginsPerYearChart:     SimpleChartFloatData{Labels: []string{}, Data: []float64{}},
-		PercentLoginsPerMonthChart:    SimpleChartFloatData{Labels: []string{}, Data: []float64{}},
-		AbandonmentPerMonthChart:      ChartData{Labels: []string{"1", "2", "6", "12"}, VerifiedData: []int{0, 0, 0, 0}, UnverifiedData: []int{0, 0, 0, 0}},
-		InactiveSixMonthsPerYearChart: ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}},
-		ActivityCohortChart:           ChartData{Labels: []string{"0", "<= 4", "> 4"}, VerifiedData: []int{0, 0, 0}, UnverifiedData: []int{0, 0, 0}},
-		ReturningUsersChart:           ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}},
-		RetentionChart:                RetentionChartData{},
-		FrictionChart:                 ChartData{Labels: []string{"< 1 day", "< 1 week", "< 1 month", "> 1 month", "Never"}, VerifiedData: []int{0, 0, 0, 0, 0}, UnverifiedData: []int{0, 0, 0, 0, 0}},
-		LoginFrequencyChart:           ChartData{Labels: []string{}, VerifiedData: make([]int, 32), UnverifiedData: make([]int, 32)},
+	input := ChartInput{
+		Users:           users,
+		Now:             now,
+		ThisYear:        thisYear,
+		MinYear:         getMinYear(users, thisYear),
+		MaxYear:         getMaxYear(users, thisYear),
+		StartYear:       startYear,
+		StartMonth:      startMonth,
+		Thresholds:      thresholds,
+		SuspiciousUsers: sync.OnceValue(func() []SuspiciousUser { return calculateSuspiciousUsers(users) }),
 	}
+	result := ChartResult{Charts: make([]ChartOutput, len(chartRegistry))}
 	var waitGroup sync.WaitGroup
 	var mutex sync.Mutex
-	runParallel(&waitGroup, &mutex, &result.TotalUsersPerYearChart, func() any { return calculateTotalUsersPerYearChart(users, minYear, maxYear) })
-	runParallel(&waitGroup, &mutex, &result.TotalUsersPerMonthChart, func() any {
-		return calculateTotalUsersPerMonthChart(users, startYear, maxYear, startMonth, now.Month())
-	})
-	runParallel(&waitGroup, &mutex, &result.NewUsersPerYearChart, func() any { return calculateNewUsersPerYearChart(users, minYear, maxYear) })
-	runParallel(&waitGroup, &mutex, &result.NewUsersPerMonthChart, func() any { return calculateNewUsersPerMonthChart(users, startYear, maxYear, startMonth, now.Month()) })
-	runParallel(&waitGroup, &mutex, &result.UserAgeChart, func() any { return calculateUserAgeChart(users, thisYear, minYear) })
-	runParallel(&waitGroup, &mutex, &result.LoginsPerYearChart, func() any { return calculateLoginsPerYearChart(users, minYear, maxYear) })
-	runParallel(&waitGroup, &mutex, &result.LoginsPerMonthChart, func() any { return calculateLoginsPerMonthChart(users, startYear, maxYear, startMonth, now.Month()) })
-	runParallel(&waitGroup, &mutex, &result.PercentLoginsPerYearChart, func() any { return calculatePercentLoginsPerYearChart(users, minYear, maxYear) })
-	runParallel(&waitGroup, &mutex, &result.PercentLoginsPerMonthChart, func() any {
-		return calculatePercentLoginsPerMonthChart(users, startYear, maxYear, startMonth, now.Month())
-	})
-	runParallel(&waitGroup, &mutex, &result.AbandonmentPerMonthChart, func() any { return calculateAbandonmentPerMonthChart(users, now) })
-	runParallel(&waitGroup, &mutex, &result.InactiveSixMonthsPerYearChart, func() any { return calculateInactiveSixMonthsPerYearChart(users, minYear, maxYear, now) })
-	runParallel(&waitGroup, &mutex, &result.ActivityCohortChart, func() any { return calculateActivityCohortChart(users) })
-	runParallel(&waitGroup, &mutex, &result.ReturningUsersChart, func() any { return calculateReturningUsersChart(users) })
-	runParallel(&waitGroup, &mutex, &result.RetentionChart, func() any { return calculateRetentionChart(users) })
-	runParallel(&waitGroup, &mutex, &result.FrictionChart, func() any { return calculateFrictionChart(users) })
-	runParallel(&waitGroup, &mutex, &result.LoginFrequencyChart, func() any { return calculateLoginFrequencyChart(users, now) })
+	for index, definition := range chartRegistry {
+		result.Charts[index] = ChartOutput{Name: definition.Name(), Title: definition.Title(), Hints: definition.Hints()}
+		runParallel(&waitGroup, &mutex, &result.Charts[index].Data, func() any { return definition.Compute(input) })
+	}
 	waitGroup.Wait()
+	result.SuspiciousUsers = input.SuspiciousUsers()
 	return result
 }
 
+func (result ChartResult) getChart(name string) (ChartOutput, bool) {
+	return lo.Find(result.Charts, func(chart ChartOutput) bool { return chart.Name == name })
+}
+
+func formatThresholds(thresholds []int) string {
+	return strings.Join(lo.Map(thresholds, func(threshold int, _ int) string { return strconv.Itoa(threshold) }), ",")
+}
+
+func registerMetricCharts(definitions []metrics.Definition) error {
+	for _, definition := range definitions {
+		if lo.ContainsBy(chartRegistry, func(chart ChartDefinition) bool { return chart.Name() == definition.Name }) {
+			return fmt.Errorf("metric %q has the same name as a built-in chart", definition.Name)
+		}
+		chartRegistry = append(chartRegistry, newChart(definition.Name, definition.Title, RenderHints{Kind: chartKindBar, XAxisTitle: definition.XAxisTitle}, func(input ChartInput) any {
+			return calculateMetricChart(definition, input.Users, input.Now)
+		}))
+	}
+	return nil
+}
+
+func validateDigestCharts(names []string) error {
+	for _, name := range names {
+		definition, exists := lo.Find(chartRegistry, func(chart ChartDefinition) bool { return chart.Name() == name })
+		if !exists {
+			return fmt.Errorf("digest: unknown chart %q", name)
+		}
+		if definition.Hints().Kind == chartKindTable {
+			return fmt.Errorf("digest: chart %q is a table, only bar charts and heatmaps can be sent", name)
+		}
+	}
+	return nil
+}
+
+func calculateMetricChart(definition metrics.Definition, users []User, now time.Time) ChartData {
+	metricUsers := lo.Map(users, func(user User, _ int) metrics.User {
+		return metrics.User{
+			Id:               user.Id,
+			IsVerified:       user.IsVerified,
+			RegisteredDate:   user.RegisteredDate,
+			Country:          user.Country,
+			EmailDomain:      user.EmailDomain,
+			IsConsumerDomain: user.IsConsumerDomain,
+			Attributes:       user.Attributes,
+			Series:           user.Series,
+			Logins: lo.Map(user.LoginRecords, func(login LoginRecord, _ int) metrics.Login {
+				return metrics.Login{Date: login.Date, Application: getLoginApplication(login), IdentityProvider: getLoginIdentityProvider(login)}
+			}),
+		}
+	})
+	result := metrics.Evaluate(definition, metricUsers, now)
+	return ChartData{
+		Labels: result.Labels,
+		Series: lo.Map(result.Series, func(series metrics.Series, _ int) ChartSeries {
+			return ChartSeries{Name: series.Name, Data: series.Data}
+		}),
+		Unit:    result.Unit,
+		Stacked: result.Stacked,
+	}
+}
+
+func writeChartCsv(writer io.Writer, chart ChartOutput) error {
+	csvWriter := csv.NewWriter(writer)
+	switch data := chart.Data.(type) {
+	case ChartData:
+		csvWriter.Write(append([]string{"label"}, lo.Map(data.Series, func(series ChartSeries, _ int) string { return series.Name })...))
+		for index, label := range data.Labels {
+			csvWriter.Write(append([]string{label}, lo.Map(data.Series, func(series ChartSeries, _ int) string {
+				return strconv.FormatFloat(series.Data[index], 'f', -1, 64)
+			})...))
+		}
+	case RetentionChartData:
+		csvWriter.Write([]string{"cohort", "month", "percent"})
+		for _, point := range data.MatrixData {
+			csvWriter.Write([]string{point.X, point.Y, strconv.FormatFloat(point.V, 'f', -1, 64)})
+		}
+	case TableData:
+		csvWriter.Write(data.Columns)
+		csvWriter.WriteAll(data.Rows)
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func newChart(name string, title string, hints RenderHints, compute func(input ChartInput) any) ChartDefinition {
+	return registeredChart{name: name, title: title, hints: hints, compute: compute}
+}
+
+func (chart registeredChart) Name() string                 { return chart.name }
+func (chart registeredChart) Title() string                { return chart.title }
+func (chart registeredChart) Hints() RenderHints           { return chart.hints }
+func (chart registeredChart) Compute(input ChartInput) any { return chart.compute(input) }
+
+var chartRegistry = []ChartDefinition{
+	newChart("totalUsersPerYearChart", "Total users per year", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateTotalUsersPerYearChart(input.Users, input.MinYear, input.MaxYear)
+	}),
+	newChart("totalUsersPerMonthChart", "Total users per month", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateTotalUsersPerMonthChart(input.Users, input.StartYear, input.MaxYear, input.StartMonth, input.Now.Month())
+	}),
+	newChart("newUsersPerYearChart", "New users per year", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateNewUsersPerYearChart(input.Users, input.MinYear, input.MaxYear)
+	}),
+	newChart("newUsersPerMonthChart", "New users per month", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateNewUsersPerMonthChart(input.Users, input.StartYear, input.MaxYear, input.StartMonth, input.Now.Month())
+	}),
+	newChart("userAgeChart", "Number of years since user registered", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateUserAgeChart(input.Users, input.ThisYear, input.MinYear)
+	}),
+	newChart("loginsPerYearChart", "Logins per year", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateLoginsPerYearChart(input.Users, input.MinYear, input.MaxYear)
+	}),
+	newChart("loginsPerMonthChart", "Logins per month", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateLoginsPerMonthChart(input.Users, input.StartYear, input.MaxYear, input.StartMonth, input.Now.Month())
+	}),
+	newChart("percentLoginsPerYearChart", "Percent logins per year", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculatePercentLoginsPerYearChart(input.Users, input.MinYear, input.MaxYear)
+	}),
+	newChart("percentLoginsPerMonthChart", "Percent logins per month", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculatePercentLoginsPerMonthChart(input.Users, input.StartYear, input.MaxYear, input.StartMonth, input.Now.Month())
+	}),
+	newChart("abandonmentPerMonthChart", "Number of users who haven't logged in for a while", RenderHints{Kind: chartKindBar, XAxisTitle: "Days since last login"}, func(input ChartInput) any {
+		return calculateAbandonmentPerMonthChart(input.Users, input.Now, input.Thresholds.AbandonmentDays)
+	}),
+	newChart("inactiveSixMonthsPerYearChart", "Number of users who haven't logged in for six months, per year", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateInactiveSixMonthsPerYearChart(input.Users, input.MinYear, input.MaxYear, input.Now)
+	}),
+	newChart("activityCohortChart", "Users grouped by login count for the past year", RenderHints{Kind: chartKindBar, XAxisTitle: "Number of logins"}, func(input ChartInput) any {
+		return calculateActivityCohortChart(input.Users, input.Now, input.Thresholds.ActivityCohortLogins)
+	}),
+	newChart("returningUsersChart", "Users returning after at least a year absent", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateReturningUsersChart(input.Users)
+	}),
+	newChart("retentionChart", "Retention heatmap: Percent users logging in in the months after registration", RenderHints{Kind: chartKindHeatmap, Wide: true, CohortName: "Cohort"}, func(input ChartInput) any {
+		return calculateRetentionChart(input.Users)
+	}),
+	newChart("frictionChart", "Friction (time until first login)", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateFrictionChart(input.Users, input.Thresholds.FrictionDays)
+	}),
+	newChart("loginFrequencyChart", "Login frequency (number of days users logged in for the past month)", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateLoginFrequencyChart(input.Users, input.Now)
+	}),
+	newChart("loginsPerApplicationChart", "Logins per application", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateLoginsPerKeyChart(input.Users, getLoginApplication)
+	}),
+	newChart("loginsPerIdentityProviderChart", "Logins per identity provider", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateLoginsPerKeyChart(input.Users, getLoginIdentityProvider)
+	}),
+	newChart("uniqueIpsPerUserChart", "Users grouped by number of unique login IP addresses", RenderHints{Kind: chartKindBar, XAxisTitle: "Number of IP addresses"}, func(input ChartInput) any {
+		return calculateUniqueIpsPerUserChart(input.Users)
+	}),
+	newChart("countryTable", "Users per country (active and new in the past year)", RenderHints{Kind: chartKindTable, WorldMap: true}, func(input ChartInput) any {
+		return getCountryTableData(calculateCountryTable(input.Users, input.Now))
+	}),
+	newChart("suspectedBotsChart", "Suspected bot accounts by reason", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateSuspectedBotsChart(input.Users)
+	}),
+	newChart("domainTypeChart", "Users by email domain type", RenderHints{Kind: chartKindBar}, func(input ChartInput) any {
+		return calculateDomainTypeChart(input.Users)
+	}),
+	newChart("domainTable", "Top email domains (active and new in the past year)", RenderHints{Kind: chartKindTable}, func(input ChartInput) any {
+		return getDomainTableData(calculateDomainTable(input.Users, input.Now))
+	}),
+	newChart("domainRetentionChart", "Retention heatmap by email domain: Percent users logging in in the months after registration", RenderHints{Kind: chartKindHeatmap, Wide: true, CohortName: "Domain"}, func(input ChartInput) any {
+		return calculateDomainRetentionChart(input.Users)
+	}),
+	newChart("suspectedBots", "Suspected bot accounts", RenderHints{Kind: chartKindTable, Scroll: true, EmptyText: "No suspected bots", MaxRows: maxTableRows}, func(input ChartInput) any {
+		return getSuspectedBotsTableData(getSuspectedBots(input.Users))
+	}),
+	newChart("suspiciousLogins", "Suspicious logins", RenderHints{Kind: chartKindTable, Scroll: true, EmptyText: "No suspicious logins", MaxRows: maxTableRows}, func(input ChartInput) any {
+		return getSuspiciousLoginsTableData(input.SuspiciousUsers())
+	}),
+}
+
 func runParallel[T any](waitGroup *sync.WaitGroup, mutex *sync.Mutex, target *T, task func() any) {
 	waitGroup.Add(1)
 	go func() {
@@ -155,122 +1837,131 @@ func runParallel[T any](waitGroup *sync.WaitGroup, mutex *sync.Mutex, target *T,
 }
 
 func calculateTotalUsersPerYearChart(users []User, minYear int, maxYear int) ChartData {
-	chart := ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}}
-	runningVerified, runningUnverified := 0, 0
+	chart := newChartData(users, []string{}, unitUsers)
+	running := make(map[string]int)
 	for year := minYear; year <= maxYear; year++ {
-		verifiedThisYear, unverifiedThisYear := getRegistrationCounts(users, year, 0, false)
-		runningVerified += verifiedThisYear
-		runningUnverified += unverifiedThisYear
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d", year))
-		chart.VerifiedData = append(chart.VerifiedData, runningVerified)
-		chart.UnverifiedData = append(chart.UnverifiedData, runningUnverified)
+		index := chart.addLabel(fmt.Sprintf("%d", year))
+		for series, count := range getRegistrationCounts(users, year, 0, false) {
+			running[series] += count
+		}
+		for series, count := range running {
+			addChartData(&chart, series, index, float64(count))
+		}
 	}
 	return chart
 }
 
 func calculateTotalUsersPerMonthChart(users []User, startYear int, maxYear int, startMonth time.Month, currentMonth time.Month) ChartData {
-	chart := ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}}
-	runningVerified, runningUnverified := 0, 0
+	chart := newChartData(users, []string{}, unitUsers)
+	running := make(map[string]int)
 	forEachMonth(startYear, maxYear, startMonth, currentMonth, func(year int, month int) {
-		verifiedThisMonth, unverifiedThisMonth := getRegistrationCounts(users, year, month, true)
-		runningVerified += verifiedThisMonth
-		runningUnverified += unverifiedThisMonth
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d-%02d", year, month))
-		chart.VerifiedData = append(chart.VerifiedData, runningVerified)
-		chart.UnverifiedData = append(chart.UnverifiedData, runningUnverified)
+		index := chart.addLabel(fmt.Sprintf("%d-%02d", year, month))
+		for series, count := range getRegistrationCounts(users, year, month, true) {
+			running[series] += count
+		}
+		for series, count := range running {
+			addChartData(&chart, series, index, float64(count))
+		}
 	})
 	return chart
 }
 
 func calculateNewUsersPerYearChart(users []User, minYear int, maxYear int) ChartData {
-	chart := ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}}
+	chart := newChartData(users, []string{}, unitUsers)
 	for year := minYear; year <= maxYear; year++ {
-		verifiedThisYear, unverifiedThisYear := getRegistrationCounts(users, year, 0, false)
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d", year))
-		chart.VerifiedData = append(chart.VerifiedData, verifiedThisYear)
-		chart.UnverifiedData = append(chart.UnverifiedData, unverifiedThisYear)
+		index := chart.addLabel(fmt.Sprintf("%d", year))
+		for series, count := range getRegistrationCounts(users, year, 0, false) {
+			addChartData(&chart, series, index, float64(count))
+		}
 	}
 	return chart
 }
 
 func calculateNewUsersPerMonthChart(users []User, startYear int, maxYear int, startMonth time.Month, currentMonth time.Month) ChartData {
-	chart := ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}}
+	chart := newChartData(users, []string{}, unitUsers)
 	forEachMonth(startYear, maxYear, startMonth, currentMonth, func(year int, month int) {
-		verifiedThisMonth, unverifiedThisMonth := getRegistrationCounts(users, year, month, true)
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d-%02d", year, month))
-		chart.VerifiedData = append(chart.VerifiedData, verifiedThisMonth)
-		chart.UnverifiedData = append(chart.UnverifiedData, unverifiedThisMonth)
+		index := chart.addLabel(fmt.Sprintf("%d-%02d", year, month))
+		for series, count := range getRegistrationCounts(users, year, month, true) {
+			addChartData(&chart, series, index, float64(count))
+		}
 	})
 	return chart
 }
 
 func calculateUserAgeChart(users []User, thisYear int, minYear int) ChartData {
 	maxAge := thisYear - minYear
-	chart := ChartData{Labels: []string{}, VerifiedData: make([]int, maxAge+1), UnverifiedData: make([]int, maxAge+1)}
+	chart := newChartData(users, []string{}, unitUsers)
+	for age := 0; age <= maxAge; age++ {
+		chart.addLabel(fmt.Sprintf("%d", age))
+	}
 	for _, user := range users {
 		age := thisYear - user.RegisteredDate.Year()
 		if age >= 0 && age <= maxAge {
-			incrementChartData(&chart, user.IsVerified, age)
+			incrementChartData(&chart, user.Series, age)
 		}
 	}
-	for age := 0; age <= maxAge; age++ {
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d", age))
-	}
 	return chart
 }
 
-func calculateLoginsPerYearChart(users []User, minYear int, maxYear int) SimpleChartData {
-	chart := SimpleChartData{Labels: []string{}, Data: []int{}}
+func calculateLoginsPerYearChart(users []User, minYear int, maxYear int) ChartData {
+	chart := newChartData(users, []string{}, unitLogins)
 	for year := minYear; year <= maxYear; year++ {
-		count := getLoginCount(users, year, 0, false)
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d", year))
-		chart.Data = append(chart.Data, count)
+		index := chart.addLabel(fmt.Sprintf("%d", year))
+		for series, count := range getLoginCounts(users, year, 0, false) {
+			addChartData(&chart, series, index, float64(count))
+		}
 	}
 	return chart
 }
 
-func calculateLoginsPerMonthChart(users []User, startYear int, maxYear int, startMonth time.Month, currentMonth time.Month) SimpleChartData {
-	chart := SimpleChartData{Labels: []string{}, Data: []int{}}
+func calculateLoginsPerMonthChart(users []User, startYear int, maxYear int, startMonth time.Month, currentMonth time.Month) ChartData {
+	chart := newChartData(users, []string{}, unitLogins)
 	forEachMonth(startYear, maxYear, startMonth, currentMonth, func(year int, month int) {
-		count := getLoginCount(users, year, month, true)
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d-%02d", year, month))
-		chart.Data = append(chart.Data, count)
+		index := chart.addLabel(fmt.Sprintf("%d-%02d", year, month))
+		for series, count := range getLoginCounts(users, year, month, true) {
+			addChartData(&chart, series, index, float64(count))
+		}
 	})
 	return chart
 }
 
-func calculatePercentLoginsPerYearChart(users []User, minYear int, maxYear int) SimpleChartFloatData {
-	chart := SimpleChartFloatData{Labels: []string{}, Data: []float64{}}
-	runningVerified, runningUnverified := 0, 0
+func calculatePercentLoginsPerYearChart(users []User, minYear int, maxYear int) ChartData {
+	chart := newChartData(users, []string{}, unitLoginsPerUser)
+	chart.Stacked = false
+	totalUsers := make(map[string]int)
 	for year := minYear; year <= maxYear; year++ {
-		verifiedThisYear, unverifiedThisYear := getRegistrationCounts(users, year, 0, false)
-		loginCount := getLoginCount(users, year, 0, false)
-		runningVerified += verifiedThisYear
-		runningUnverified += unverifiedThisYear
-		totalUsers := runningVerified + runningUnverified
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d", year))
-		chart.Data = append(chart.Data, calculateRatio(loginCount, totalUsers))
+		index := chart.addLabel(fmt.Sprintf("%d", year))
+		for series, count := range getRegistrationCounts(users, year, 0, false) {
+			totalUsers[series] += count
+		}
+		loginCounts := getLoginCounts(users, year, 0, false)
+		for series, count := range totalUsers {
+			addChartData(&chart, series, index, calculateRatio(loginCounts[series], count))
+		}
 	}
 	return chart
 }
 
-func calculatePercentLoginsPerMonthChart(users []User, startYear int, maxYear int, startMonth time.Month, currentMonth time.Month) SimpleChartFloatData {
-	chart := SimpleChartFloatData{Labels: []string{}, Data: []float64{}}
-	runningVerified, runningUnverified := 0, 0
+func calculatePercentLoginsPerMonthChart(users []User, startYear int, maxYear int, startMonth time.Month, currentMonth time.Month) ChartData {
+	chart := newChartData(users, []string{}, unitLoginsPerUser)
+	chart.Stacked = false
+	totalUsers := make(map[string]int)
 	forEachMonth(startYear, maxYear, startMonth, currentMonth, func(year int, month int) {
-		verifiedThisMonth, unverifiedThisMonth := getRegistrationCounts(users, year, month, true)
-		loginCount := getLoginCount(users, year, month, true)
-		runningVerified += verifiedThisMonth
-		runningUnverified += unverifiedThisMonth
-		totalUsers := runningVerified + runningUnverified
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d-%02d", year, month))
-		chart.Data = append(chart.Data, calculateRatio(loginCount, totalUsers))
+		index := chart.addLabel(fmt.Sprintf("%d-%02d", year, month))
+		for series, count := range getRegistrationCounts(users, year, month, true) {
+			totalUsers[series] += count
+		}
+		loginCounts := getLoginCounts(users, year, month, true)
+		for series, count := range totalUsers {
+			addChartData(&chart, series, index, calculateRatio(loginCounts[series], count))
+		}
 	})
 	return chart
 }
 
-func calculateAbandonmentPerMonthChart(users []User, now time.Time) ChartData {
-	chart := ChartData{Labels: []string{"1", "2", "6", "12"}, VerifiedData: make([]int, 4), UnverifiedData: make([]int, 4)}
+func calculateAbandonmentPerMonthChart(users []User, now time.Time, thresholdDays []int) ChartData {
+	labels := lo.Map(thresholdDays, func(days int, _ int) string { return fmt.Sprintf("%d+", days) })
+	chart := newChartData(users, labels, unitUsers)
 	for _, user := range users {
 		if len(user.LoginDates) == 0 {
 			continue
@@ -278,27 +1969,23 @@ func calculateAbandonmentPerMonthChart(users []User, now time.Time) ChartData {
 		mostRecent := user.LoginDates[len(user.LoginDates)-1]
 		differenceDays := int(now.Sub(mostRecent).Hours() / 24)
 		index := -1
-		if differenceDays >= 365 {
-			index = 3
-		} else if differenceDays >= 182 {
-			index = 2
-		} else if differenceDays >= 60 {
-			index = 1
-		} else if differenceDays >= 30 {
-			index = 0
+		for thresholdIndex, days := range thresholdDays {
+			if differenceDays >= days {
+				index = thresholdIndex
+			}
 		}
 		if index != -1 {
-			incrementChartData(&chart, user.IsVerified, index)
+			incrementChartData(&chart, user.Series, index)
 		}
 	}
 	return chart
 }
 
 func calculateInactiveSixMonthsPerYearChart(users []User, minYear int, maxYear int, now time.Time) ChartData {
-	chart := ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}}
+	chart := newChartData(users, []string{}, unitUsers)
 	sixMonthDuration := 6 * 30 * 24 * time.Hour
 	for year := minYear; year <= maxYear; year++ {
-		verifiedLapsedCount, unverifiedLapsedCount := 0, 0
+		index := chart.addLabel(fmt.Sprintf("%d", year))
 		yearStart := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
 		yearEnd := time.Date(year, 12, 31, 23, 59, 59, 0, time.UTC)
 		observationEnd := yearEnd
@@ -323,70 +2010,75 @@ func calculateInactiveSixMonthsPerYearChart(users []User, minYear int, maxYear i
 				}
 			}
 			if isUserCurrentlyLapsed {
-				if user.IsVerified {
-					verifiedLapsedCount++
-				} else {
-					unverifiedLapsedCount++
-				}
+				incrementChartData(&chart, user.Series, index)
 			}
 		}
-		chart.Labels = append(chart.Labels, fmt.Sprintf("%d", year))
-		chart.VerifiedData = append(chart.VerifiedData, verifiedLapsedCount)
-		chart.UnverifiedData = append(chart.UnverifiedData, unverifiedLapsedCount)
 	}
 	return chart
 }
 
-func calculateActivityCohortChart(users []User) ChartData {
-	chart := ChartData{Labels: []string{"0", "<= 4", "> 4"}, VerifiedData: make([]int, 3), UnverifiedData: make([]int, 3)}
-	now := time.Now()
+func calculateActivityCohortChart(users []User, now time.Time, thresholdLogins []int) ChartData {
+	labels := []string{"0"}
+	for index, logins := range thresholdLogins {
+		lower := 1
+		if index > 0 {
+			lower = thresholdLogins[index-1] + 1
+		}
+		labels = append(labels, lo.Ternary(lower == logins, fmt.Sprintf("%d", logins), fmt.Sprintf("%d-%d", lower, logins)))
+	}
+	labels = append(labels, fmt.Sprintf("> %d", thresholdLogins[len(thresholdLogins)-1]))
+	chart := newChartData(users, labels, unitUsers)
 	oneYearAgo := now.AddDate(-1, 0, 0)
 	for _, user := range users {
 		loginsInPastYearCount := len(lo.Filter(user.LoginDates, func(loginTimestamp time.Time, _ int) bool {
 			return loginTimestamp.After(oneYearAgo) || loginTimestamp.Equal(oneYearAgo)
 		}))
-		cohortIndex := 0
-		if loginsInPastYearCount > 4 {
-			cohortIndex = 2
-		} else if loginsInPastYearCount > 0 {
-			cohortIndex = 1
+		cohortIndex := len(thresholdLogins) + 1
+		if loginsInPastYearCount == 0 {
+			cohortIndex = 0
+		} else if index := slices.IndexFunc(thresholdLogins, func(logins int) bool { return loginsInPastYearCount <= logins }); index != -1 {
+			cohortIndex = index + 1
 		}
-		incrementChartData(&chart, user.IsVerified, cohortIndex)
+		incrementChartData(&chart, user.Series, cohortIndex)
 	}
 	return chart
 }
 
 func calculateReturningUsersChart(users []User) ChartData {
-	chart := ChartData{Labels: []string{}, VerifiedData: []int{}, UnverifiedData: []int{}}
-	labelMap := make(map[string]int)
+	counts := make(map[string]map[string]int)
 	for _, user := range users {
 		previous := user.RegisteredDate
 		for _, login := range user.LoginDates {
 			if login.Sub(previous).Hours() > 24*365 {
 				label := login.Format("2006-01")
-				if _, exists := labelMap[label]; !exists {
-					chart.Labels = append(chart.Labels, label)
-					sort.Strings(chart.Labels)
-					chart.VerifiedData = make([]int, len(chart.Labels))
-					chart.UnverifiedData = make([]int, len(chart.Labels))
-					for index, labelValue := range chart.Labels {
-						labelMap[labelValue] = index
-					}
+				if _, exists := counts[label]; !exists {
+					counts[label] = make(map[string]int)
 				}
-				index := labelMap[label]
-				incrementChartData(&chart, user.IsVerified, index)
+				counts[label][user.Series]++
 			}
 			previous = login
 		}
 	}
+	labels := lo.Keys(counts)
+	sort.Strings(labels)
+	chart := newChartData(users, labels, unitUsers)
+	for index, label := range labels {
+		for series, count := range counts[label] {
+			addChartData(&chart, series, index, float64(count))
+		}
+	}
 	return chart
 }
 
 func calculateRetentionChart(users []User) RetentionChartData {
+	return calculateRetentionChartByKey(users, func(user User) string { return user.RegisteredDate.Format("2006-01") })
+}
+
+func calculateRetentionChartByKey(users []User, getKey func(user User) string) RetentionChartData {
 	maxMonths := 12
 	cohorts := make(map[string]*CohortData)
 	for _, user := range users {
-		key := user.RegisteredDate.Format("2006-01")
+		key := getKey(user)
 		if _, exists := cohorts[key]; !exists {
 			cohorts[key] = &CohortData{Counts: make([]int, maxMonths+1)}
 		}
@@ -421,32 +2113,31 @@ func calculateRetentionChart(users []User) RetentionChartData {
 	return chart
 }
 
-func calculateFrictionChart(users []User) ChartData {
-	chart := ChartData{Labels: []string{"< 1 day", "< 1 week", "< 1 month", "> 1 month", "Never"}, VerifiedData: make([]int, 5), UnverifiedData: make([]int, 5)}
-	day, week, month := 24*time.Hour, 7*24*time.Hour, 30*24*time.Hour
+func calculateFrictionChart(users []User, thresholdDays []int) ChartData {
+	labels := lo.Map(thresholdDays, func(days int, _ int) string {
+		return fmt.Sprintf("<= %d %s", days, lo.Ternary(days == 1, "day", "days"))
+	})
+	labels = append(labels, fmt.Sprintf("> %d days", thresholdDays[len(thresholdDays)-1]), "Never")
+	chart := newChartData(users, labels, unitUsers)
 	for _, user := range users {
 		if len(user.LoginDatesUniqueMonthly) == 0 {
-			incrementChartData(&chart, user.IsVerified, 4)
+			incrementChartData(&chart, user.Series, len(thresholdDays)+1)
 			continue
 		}
 		difference := user.LoginDatesUniqueMonthly[0].Sub(user.RegisteredDate)
-		index := 3
-		if difference <= day {
-			index = 0
-		} else if difference <= week {
-			index = 1
-		} else if difference <= month {
-			index = 2
+		index := slices.IndexFunc(thresholdDays, func(days int) bool { return difference <= time.Duration(days)*24*time.Hour })
+		if index == -1 {
+			index = len(thresholdDays)
 		}
-		incrementChartData(&chart, user.IsVerified, index)
+		incrementChartData(&chart, user.Series, index)
 	}
 	return chart
 }
 
 func calculateLoginFrequencyChart(users []User, now time.Time) ChartData {
-	chart := ChartData{Labels: make([]string, 32), VerifiedData: make([]int, 32), UnverifiedData: make([]int, 32)}
+	chart := newChartData(users, []string{}, unitUsers)
 	for i := 0; i <= 31; i++ {
-		chart.Labels[i] = fmt.Sprintf("%d", i)
+		chart.addLabel(fmt.Sprintf("%d", i))
 	}
 	thirtyDaysAgo := now.Add(-30 * 24 * time.Hour)
 	for _, user := range users {
@@ -460,11 +2151,235 @@ func calculateLoginFrequencyChart(users []User, now time.Time) ChartData {
 		if count > 31 {
 			count = 31
 		}
-		incrementChartData(&chart, user.IsVerified, count)
+		incrementChartData(&chart, user.Series, count)
+	}
+	return chart
+}
+
+func calculateLoginsPerKeyChart(users []User, getKey func(login LoginRecord) string) ChartData {
+	counts := make(map[string]map[string]int)
+	for _, user := range users {
+		for _, login := range user.LoginRecords {
+			key := getKey(login)
+			if _, exists := counts[key]; !exists {
+				counts[key] = make(map[string]int)
+			}
+			counts[key][user.Series]++
+		}
+	}
+	labels := lo.Keys(counts)
+	sort.Strings(labels)
+	chart := newChartData(users, labels, unitLogins)
+	for index, label := range labels {
+		for series, count := range counts[label] {
+			addChartData(&chart, series, index, float64(count))
+		}
+	}
+	return chart
+}
+
+func calculateUniqueIpsPerUserChart(users []User) ChartData {
+	maxBucket := 10
+	chart := newChartData(users, []string{}, unitUsers)
+	for count := 1; count <= maxBucket; count++ {
+		chart.addLabel(fmt.Sprintf("%d", count))
+	}
+	chart.Labels[maxBucket-1] = fmt.Sprintf("%d+", maxBucket)
+	for _, user := range users {
+		ipAddresses := lo.Uniq(lo.FilterMap(user.LoginRecords, func(login LoginRecord, _ int) (string, bool) {
+			return login.IpAddress, login.IpAddress != ""
+		}))
+		if len(ipAddresses) == 0 {
+			continue
+		}
+		incrementChartData(&chart, user.Series, min(len(ipAddresses), maxBucket)-1)
+	}
+	return chart
+}
+
+func calculateCountryTable(users []User, now time.Time) []CountryRow {
+	oneYearAgo := now.AddDate(-1, 0, 0)
+	rows := make(map[string]*CountryRow)
+	getRow := func(country string) *CountryRow {
+		if _, exists := rows[country]; !exists {
+			rows[country] = &CountryRow{Country: country}
+		}
+		return rows[country]
+	}
+	for _, user := range users {
+		if len(user.LoginDates) > 0 && !user.LoginDates[len(user.LoginDates)-1].Before(oneYearAgo) {
+			getRow(user.Country).ActiveUsers++
+		}
+		registrationRow := getRow(user.RegistrationCountry)
+		registrationRow.TotalUsers++
+		if !user.RegisteredDate.Before(oneYearAgo) {
+			registrationRow.NewUsers++
+		}
+	}
+	table := lo.Map(lo.Values(rows), func(row *CountryRow, _ int) CountryRow { return *row })
+	sort.Slice(table, func(i, j int) bool {
+		if table[i].ActiveUsers != table[j].ActiveUsers {
+			return table[i].ActiveUsers > table[j].ActiveUsers
+		}
+		if table[i].TotalUsers != table[j].TotalUsers {
+			return table[i].TotalUsers > table[j].TotalUsers
+		}
+		return table[i].Country < table[j].Country
+	})
+	return table
+}
+
+func getCountryTableData(rows []CountryRow) TableData {
+	table := TableData{Columns: []string{"Country", "Active users", "New users", "All users"}, Rows: [][]string{}}
+	for _, row := range rows {
+		table.Rows = append(table.Rows, []string{row.Country, fmt.Sprintf("%d", row.ActiveUsers), fmt.Sprintf("%d", row.NewUsers), fmt.Sprintf("%d", row.TotalUsers)})
+	}
+	return table
+}
+
+func getDomainTableData(rows []DomainRow) TableData {
+	table := TableData{Columns: []string{"Domain", "Type", "Active users", "New users", "All users", "Active %"}, Rows: [][]string{}}
+	for _, row := range rows {
+		domainType := lo.Ternary(row.IsConsumer, "Consumer", "Corporate")
+		table.Rows = append(table.Rows, []string{row.Domain, domainType, fmt.Sprintf("%d", row.ActiveUsers), fmt.Sprintf("%d", row.NewUsers), fmt.Sprintf("%d", row.TotalUsers), fmt.Sprintf("%g", row.ActivePercent)})
+	}
+	return table
+}
+
+func getSuspectedBotsTableData(bots []security.BotScore) TableData {
+	table := TableData{Columns: []string{"User", "Score", "Reasons"}, Rows: [][]string{}}
+	for _, bot := range bots {
+		table.Rows = append(table.Rows, []string{bot.Email, fmt.Sprintf("%.2f", bot.Score), strings.Join(bot.Reasons, ", ")})
+	}
+	return table
+}
+
+func getSuspiciousLoginsTableData(suspiciousUsers []SuspiciousUser) TableData {
+	table := TableData{Columns: []string{"User", "Finding", "Evidence", "When"}, Rows: [][]string{}}
+	for _, user := range suspiciousUsers {
+		for _, finding := range user.Findings {
+			ipAddresses := lo.Uniq(lo.Map(finding.Logins, func(login security.Login, _ int) string { return login.IpAddress }))
+			evidence := fmt.Sprintf("%s (%s)", finding.Description, strings.Join(ipAddresses, ", "))
+			table.Rows = append(table.Rows, []string{user.Email, lo.ValueOr(findingTypeNames, finding.Type, finding.Type), evidence, finding.Start.Format("2006-01-02 15:04")})
+		}
+	}
+	return table
+}
+
+func calculateSuspiciousUsers(users []User) []SuspiciousUser {
+	suspiciousUsers := []SuspiciousUser{}
+	options := security.DefaultOptions()
+	for _, user := range users {
+		logins := lo.Map(user.LoginRecords, func(record LoginRecord, _ int) security.Login {
+			return security.Login{
+				Time:             record.Date,
+				IpAddress:        record.IpAddress,
+				Country:          record.Country,
+				Latitude:         record.Latitude,
+				Longitude:        record.Longitude,
+				AccuracyRadiusKm: record.AccuracyRadiusKm,
+				HasCoordinates:   record.HasCoordinates,
+			}
+		})
+		findings := security.DetectSuspiciousLogins(logins, options)
+		if len(findings) > 0 {
+			suspiciousUsers = append(suspiciousUsers, SuspiciousUser{Id: user.Id, Email: user.Email, Findings: findings})
+		}
+	}
+	sort.Slice(suspiciousUsers, func(i, j int) bool {
+		if len(suspiciousUsers[i].Findings) != len(suspiciousUsers[j].Findings) {
+			return len(suspiciousUsers[i].Findings) > len(suspiciousUsers[j].Findings)
+		}
+		return suspiciousUsers[i].Email < suspiciousUsers[j].Email
+	})
+	return suspiciousUsers
+}
+
+func calculateSuspectedBotsChart(users []User) ChartData {
+	reasons := []string{security.BotReasonSequentialEmail, security.BotReasonRandomEmail, security.BotReasonDisposableDomain, security.BotReasonClusteredSignup, security.BotReasonNeverVerified, security.BotReasonNeverLoggedIn}
+	chart := newChartData(users, []string{"Sequential email", "Random-looking email", "Disposable domain", "Clustered signup", "Never verified", "Never logged in"}, unitUsers)
+	for _, user := range users {
+		if !user.BotScore.IsBot {
+			continue
+		}
+		for _, reason := range user.BotScore.Reasons {
+			incrementChartData(&chart, user.Series, lo.IndexOf(reasons, reason))
+		}
+	}
+	return chart
+}
+
+func getSuspectedBots(users []User) []security.BotScore {
+	bots := lo.FilterMap(users, func(user User, _ int) (security.BotScore, bool) { return user.BotScore, user.BotScore.IsBot })
+	sort.SliceStable(bots, func(i, j int) bool { return bots[i].Score > bots[j].Score })
+	return bots
+}
+
+func calculateDomainTypeChart(users []User) ChartData {
+	chart := newChartData(users, []string{"Consumer", "Corporate"}, unitUsers)
+	for _, user := range users {
+		index := 1
+		if user.IsConsumerDomain {
+			index = 0
+		}
+		incrementChartData(&chart, user.Series, index)
 	}
 	return chart
 }
 
+func calculateDomainTable(users []User, now time.Time) []DomainRow {
+	oneYearAgo := now.AddDate(-1, 0, 0)
+	rows := make(map[string]*DomainRow)
+	for _, user := range users {
+		if _, exists := rows[user.EmailDomain]; !exists {
+			rows[user.EmailDomain] = &DomainRow{Domain: user.EmailDomain, IsConsumer: user.IsConsumerDomain}
+		}
+		row := rows[user.EmailDomain]
+		row.TotalUsers++
+		if !user.RegisteredDate.Before(oneYearAgo) {
+			row.NewUsers++
+		}
+		if len(user.LoginDates) > 0 && !user.LoginDates[len(user.LoginDates)-1].Before(oneYearAgo) {
+			row.ActiveUsers++
+		}
+	}
+	table := lo.Map(lo.Values(rows), func(row *DomainRow, _ int) DomainRow {
+		row.ActivePercent = math.Round(calculateRatio(row.ActiveUsers, row.TotalUsers)*1000) / 10
+		return *row
+	})
+	sort.Slice(table, func(i, j int) bool {
+		if table[i].TotalUsers != table[j].TotalUsers {
+			return table[i].TotalUsers > table[j].TotalUsers
+		}
+		return table[i].Domain < table[j].Domain
+	})
+	return table[:min(len(table), topDomainCount)]
+}
+
+func calculateDomainRetentionChart(users []User) RetentionChartData {
+	topDomains := getDomainsByUserCount(users)
+	topDomains = topDomains[:min(len(topDomains), topDomainCount)]
+	topDomainUsers := lo.Filter(users, func(user User, _ int) bool { return lo.Contains(topDomains, user.EmailDomain) })
+	return calculateRetentionChartByKey(topDomainUsers, func(user User) string { return user.EmailDomain })
+}
+
+func getLoginApplication(login LoginRecord) string {
+	if login.ApplicationName != "" {
+		return login.ApplicationName
+	}
+	if login.ApplicationId != "" {
+		return login.ApplicationId
+	}
+	return "Unknown"
+}
+
+func getLoginIdentityProvider(login LoginRecord) string {
+	if login.IdentityProvider != "" {
+		return login.IdentityProvider
+	}
+	return "Unknown"
+}
+
 func getMinYear(users []User, currentYear int) int {
 	min := currentYear
 	for _, user := range users {
@@ -514,12 +2429,38 @@ func forEachMonth(startYear int, maxYear int, startMonth time.Month, currentMont
 	}
 }
 
-func incrementChartData(chart *ChartData, isVerified bool, index int) {
-	if isVerified {
-		chart.VerifiedData[index]++
-	} else {
-		chart.UnverifiedData[index]++
+func newChartData(users []User, labels []string, unit string) ChartData {
+	chart := ChartData{Labels: []string{}, Series: []ChartSeries{}, Unit: unit, Stacked: true}
+	seriesNames := lo.Uniq(lo.Map(users, func(user User, _ int) string { return user.Series }))
+	sort.Strings(seriesNames)
+	for _, name := range seriesNames {
+		chart.Series = append(chart.Series, ChartSeries{Name: name, Data: []float64{}})
 	}
+	for _, label := range labels {
+		chart.addLabel(label)
+	}
+	return chart
+}
+
+func (chart *ChartData) addLabel(label string) int {
+	chart.Labels = append(chart.Labels, label)
+	for index := range chart.Series {
+		chart.Series[index].Data = append(chart.Series[index].Data, 0)
+	}
+	return len(chart.Labels) - 1
+}
+
+func addChartData(chart *ChartData, series string, index int, amount float64) {
+	for seriesIndex := range chart.Series {
+		if chart.Series[seriesIndex].Name == series {
+			chart.Series[seriesIndex].Data[index] += amount
+			return
+		}
+	}
+}
+
+func incrementChartData(chart *ChartData, series string, index int) {
+	addChartData(chart, series, index, 1)
 }
 
 func calculateRatio(numerator int, denominator int) float64 {
@@ -529,66 +2470,218 @@ func calculateRatio(numerator int, denominator int) float64 {
 	return float64(numerator) / float64(denominator)
 }
 
-func getRegistrationCounts(users []User, year int, month int, useMonthly bool) (int, int) {
-	verified := len(lo.Filter(users, func(user User, _ int) bool {
+func getRegistrationCounts(users []User, year int, month int, useMonthly bool) map[string]int {
+	counts := make(map[string]int)
+	for _, user := range users {
 		match := user.RegisteredDate.Year() == year
 		if useMonthly {
 			match = match && int(user.RegisteredDate.Month()) == month
 		}
-		return match && user.IsVerified
-	}))
-	unverified := len(lo.Filter(users, func(user User, _ int) bool {
-		match := user.RegisteredDate.Year() == year
-		if useMonthly {
-			match = match && int(user.RegisteredDate.Month()) == month
+		if match {
+			counts[user.Series]++
 		}
-		return match && !user.IsVerified
-	}))
-	return verified, unverified
+	}
+	return counts
 }
 
-func getLoginCount(users []User, year int, month int, useMonthly bool) int {
-	return lo.Reduce(users, func(accumulator int, user User, _ int) int {
+func getLoginCounts(users []User, year int, month int, useMonthly bool) map[string]int {
+	counts := make(map[string]int)
+	for _, user := range users {
 		dates := user.LoginDatesUniqueYearly
 		if useMonthly {
 			dates = user.LoginDatesUniqueMonthly
 		}
-		return accumulator + len(lo.Filter(dates, func(timestamp time.Time, _ int) bool {
+		counts[user.Series] += len(lo.Filter(dates, func(timestamp time.Time, _ int) bool {
 			match := timestamp.Year() == year
 			if useMonthly {
 				match = match && int(timestamp.Month()) == month
 			}
 			return match
 		}))
-	}, 0)
+	}
+	return counts
 }
 
 type User struct {
-	Id                      string      `json:"id"`
-	Email                   string      `json:"email"`
-	IsVerified              bool        `json:"isVerified"`
-	RegisteredDateRaw       int64       `json:"registeredDate"`
-	RegisteredDate          time.Time   `json:"-"`
-	LoginDatesRaw           []int64     `json:"loginDates"`
-	LoginDates              []time.Time `json:"-"`
-	LoginDatesUniqueMonthly []time.Time `json:"-"`
-	LoginDatesUniqueYearly  []time.Time `json:"-"`
+	Id                      string            `json:"id"`
+	Email                   string            `json:"email"`
+	IsVerified              bool              `json:"isVerified"`
+	RegisteredDateRaw       int64             `json:"registeredDate"`
+	RegisteredDate          time.Time         `json:"-"`
+	LoginDatesRaw           []int64           `json:"loginDates"`
+	LoginDates              []time.Time       `json:"-"`
+	LoginDatesUniqueMonthly []time.Time       `json:"-"`
+	LoginDatesUniqueYearly  []time.Time       `json:"-"`
+	LoginRecords            []LoginRecord     `json:"logins"`
+	Country                 string            `json:"-"`
+	RegistrationCountry     string            `json:"-"`
+	BotScore                security.BotScore `json:"-"`
+	EmailDomain             string            `json:"-"`
+	IsConsumerDomain        bool              `json:"-"`
+	Attributes              map[string]string `json:"attributes"`
+	Series                  string            `json:"-"`
 }
 
-type ChartData struct {
-	Labels         []string `json:"labels"`
-	VerifiedData   []int    `json:"verifiedData"`
-	UnverifiedData []int    `json:"unverifiedData"`
+type LoginRecord struct {
+	Instant          int64     `json:"instant"`
+	Date             time.Time `json:"-"`
+	IpAddress        string    `json:"ipAddress"`
+	ApplicationId    string    `json:"applicationId"`
+	ApplicationName  string    `json:"applicationName"`
+	IdentityProvider string    `json:"identityProvider"`
+	Country          string    `json:"-"`
+	Region           string    `json:"-"`
+	Latitude         float64   `json:"-"`
+	Longitude        float64   `json:"-"`
+	AccuracyRadiusKm float64   `json:"-"`
+	HasCoordinates   bool      `json:"-"`
+}
+
+type SuspiciousUser struct {
+	Id       string             `json:"id"`
+	Email    string             `json:"email"`
+	Findings []security.Finding `json:"findings"`
+}
+
+type DomainRow struct {
+	Domain        string  `json:"domain"`
+	IsConsumer    bool    `json:"isConsumer"`
+	TotalUsers    int     `json:"totalUsers"`
+	NewUsers      int     `json:"newUsers"`
+	ActiveUsers   int     `json:"activeUsers"`
+	ActivePercent float64 `json:"activePercent"`
+}
+
+type ViewFilter struct {
+	Country         string
+	Domain          string
+	ExcludeBots     bool
+	SplitBy         string
+	CohortLogins    string
+	FrictionDays    string
+	AbandonmentDays string
+}
+
+type SplitOption struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+type Dashboard struct {
+	users           []User
+	countries       []string
+	domains         []string
+	splitOptions    []SplitOption
+	thresholds      config.ChartsConfig
+	extractedAt     time.Time
+	loadedAt        time.Time
+	computeDuration time.Duration
+	chartDataByView map[ViewFilter]*cachedView
+	mutex           sync.Mutex
+}
+
+type cachedView struct {
+	thresholds config.ChartsConfig
+	chartData  func() ChartResult
+}
+
+type StaticSite struct {
+	ExportedAt time.Time    `json:"exportedAt"`
+	Segment    string       `json:"segment"`
+	Segments   []StaticPage `json:"segments"`
+}
+
+type StaticPage struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	File  string `json:"file"`
+}
+
+type LiveDashboard struct {
+	thresholds           config.ChartsConfig
+	options              extract.Options
+	records              []extract.User
+	recordIndex          map[string]int
+	changedIds           []string
+	geoIp                *geoip.Reader
+	geoIpOnce            sync.Once
+	refresh              chan struct{}
+	alerts               *alerts.Manager
+	webhookEvents        atomic.Int64
+	webhookEventsApplied atomic.Int64
+	subscribers          map[chan struct{}]bool
+	subscriberMutex      sync.Mutex
+	dashboard            atomic.Pointer[Dashboard]
+	reloadMutex          sync.Mutex
+	events               []receivedEvent
+	eventIds             map[string]bool
+	eventIdOrder         []string
+}
+
+type receivedEvent struct {
+	event      webhook.Event
+	receivedAt time.Time
+}
+
+type ExtractScheduler struct {
+	enabled       bool
+	schedule      schedule.Schedule
+	client        *fusionauth.Client
+	options       extract.Options
+	dashboard     *LiveDashboard
+	trigger       chan struct{}
+	previousUsers []extract.User
+	previousStart time.Time
+	status        ExtractStatus
+	statusMutex   sync.Mutex
+}
+
+type DigestScheduler struct {
+	enabled   bool
+	schedule  schedule.Schedule
+	config    config.DigestConfig
+	dashboard *LiveDashboard
+}
+
+type ExtractStatus struct {
+	Schedule    string       `json:"schedule"`
+	Running     bool         `json:"running"`
+	NextRun     time.Time    `json:"nextRun"`
+	Users       int          `json:"users"`
+	ExtractedAt time.Time    `json:"extractedAt"`
+	LoadedAt    time.Time    `json:"loadedAt"`
+	Runs        []ExtractRun `json:"runs"`
+	Successful  int          `json:"successful"`
+	Failed      int          `json:"failed"`
+	NeedsToken  bool         `json:"-"`
+}
+
+type ExtractRun struct {
+	Start       time.Time `json:"start"`
+	DurationMs  int64     `json:"durationMs"`
+	Users       int       `json:"users"`
+	NewLogins   int       `json:"newLogins"`
+	Incremental bool      `json:"incremental"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type CountryRow struct {
+	Country     string `json:"country"`
+	ActiveUsers int    `json:"activeUsers"`
+	NewUsers    int    `json:"newUsers"`
+	TotalUsers  int    `json:"totalUsers"`
 }
 
-type SimpleChartData struct {
-	Labels []string `json:"labels"`
-	Data   []int    `json:"data"`
+type ChartData struct {
+	Labels  []string      `json:"labels"`
+	Series  []ChartSeries `json:"series"`
+	Unit    string        `json:"unit"`
+	Stacked bool          `json:"stacked"`
 }
 
-type SimpleChartFloatData struct {
-	Labels []string  `json:"labels"`
-	Data   []float64 `json:"data"`
+type ChartSeries struct {
+	Name string    `json:"name"`
+	Data []float64 `json:"data"`
 }
 
 type MatrixPoint struct {
@@ -609,20 +2702,66 @@ type CohortData struct {
 }
 
 type ChartResult struct {
-	TotalUsersPerYearChart        ChartData            `json:"totalUsersPerYearChart"`
-	TotalUsersPerMonthChart       ChartData            `json:"totalUsersPerMonthChart"`
-	NewUsersPerYearChart          ChartData            `json:"newUsersPerYearChart"`
-	NewUsersPerMonthChart         ChartData            `json:"newUsersPerMonthChart"`
-	UserAgeChart                  ChartData            `json:"userAgeChart"`
-	LoginsPerYearChart            SimpleChartData      `json:"loginsPerYearChart"`
-	LoginsPerMonthChart           SimpleChartData      `json:"loginsPerMonthChart"`
-	PercentLoginsPerYearChart     SimpleChartFloatData `json:"percentLoginsPerYearChart"`
-	PercentLoginsPerMonthChart    SimpleChartFloatData `json:"percentLoginsPerMonthChart"`
-	AbandonmentPerMonthChart      ChartData            `json:"abandonmentPerMonthChart"`
-	InactiveSixMonthsPerYearChart ChartData            `json:"inactiveSixMonthsPerYearChart"`
-	ActivityCohortChart           ChartData            `json:"activityCohortChart"`
-	ReturningUsersChart           ChartData            `json:"returningUsersChart"`
-	RetentionChart                RetentionChartData   `json:"retentionChart"`
-	FrictionChart                 ChartData            `json:"frictionChart"`
-	LoginFrequencyChart           ChartData            `json:"loginFrequencyChart"`
+	Charts            []ChartOutput       `json:"charts"`
+	SuspiciousUsers   []SuspiciousUser    `json:"-"`
+	Countries         []string            `json:"countries"`
+	SelectedCountry   string              `json:"selectedCountry"`
+	ExcludeBots       bool                `json:"excludeBots"`
+	Domains           []string            `json:"domains"`
+	SelectedDomain    string              `json:"selectedDomain"`
+	SplitBy           string              `json:"splitBy"`
+	SplitOptions      []SplitOption       `json:"splitOptions"`
+	Thresholds        config.ChartsConfig `json:"thresholds"`
+	DefaultThresholds config.ChartsConfig `json:"defaultThresholds"`
+	ExtractedAt       time.Time           `json:"extractedAt"`
+	LoadedAt          time.Time           `json:"loadedAt"`
+}
+
+type ChartOutput struct {
+	Name  string      `json:"name"`
+	Title string      `json:"title"`
+	Hints RenderHints `json:"hints"`
+	Data  any         `json:"data,omitempty"`
+}
+
+type ChartDefinition interface {
+	Name() string
+	Title() string
+	Hints() RenderHints
+	Compute(input ChartInput) any
+}
+
+type registeredChart struct {
+	name    string
+	title   string
+	hints   RenderHints
+	compute func(input ChartInput) any
+}
+
+type ChartInput struct {
+	Users           []User
+	Now             time.Time
+	ThisYear        int
+	MinYear         int
+	MaxYear         int
+	StartYear       int
+	StartMonth      time.Month
+	Thresholds      config.ChartsConfig
+	SuspiciousUsers func() []SuspiciousUser
+}
+
+type RenderHints struct {
+	Kind       string `json:"kind"`
+	Wide       bool   `json:"wide,omitempty"`
+	Scroll     bool   `json:"scroll,omitempty"`
+	XAxisTitle string `json:"xAxisTitle,omitempty"`
+	CohortName string `json:"cohortName,omitempty"`
+	EmptyText  string `json:"emptyText,omitempty"`
+	MaxRows    int    `json:"maxRows,omitempty"`
+	WorldMap   bool   `json:"worldMap,omitempty"`
+}
+
+type TableData struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
 }
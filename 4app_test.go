@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"app/config"
+	"app/extract"
+	"app/fakefusionauth"
+	"app/fusionauth"
+	"app/mockdata"
+	"app/prometheus"
+	"app/webhook"
+
+	"github.com/samber/lo"
+)
+
+const testApiKey = "test-api-key"
+const testApplicationId = "test-application"
+
+func TestExtractAndServePipeline(t *testing.T) {
+	server := fakefusionauth.New(fakefusionauth.Options{ApiKey: testApiKey, MaxResults: 2})
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	addTestUser(server, "a@example.com", true, testApplicationId, date(2023, 3, 10), date(2023, 5, 1), date(2024, 2, 1), date(2024, 6, 1))
+	addTestUser(server, "b@example.com", true, testApplicationId, date(2024, 1, 15), date(2024, 3, 1))
+	addTestUser(server, "c@example.com", false, testApplicationId, date(2024, 7, 1))
+	addTestUser(server, "other@example.com", true, "other-application", date(2024, 2, 1), date(2024, 2, 2))
+
+	client := fusionauth.NewClient(httpServer.URL, testApiKey)
+	client.PageSize = 2
+	result, err := extract.Run(context.Background(), client, extract.Options{ApplicationId: testApplicationId}, nil, time.Time{})
+	if err != nil {
+		t.Fatalf("extract.Run: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), usersFile)
+	if err := extract.WriteJson(path, result.Users); err != nil {
+		t.Fatalf("WriteJson: %v", err)
+	}
+	records, err := extract.ReadUsers(path)
+	if err != nil {
+		t.Fatalf("ReadUsers: %v", err)
+	}
+	users, err := getUsers(records, nil)
+	if err != nil {
+		t.Fatalf("getUsers: %v", err)
+	}
+	if len(users) != 3 {
+		t.Fatalf("got %d users, want 3 registered for the application", len(users))
+	}
+	dashboard := newDashboard(users, config.DefaultChartsConfig(), time.Now())
+	chartData, err := dashboard.getChartDataForQuery(url.Values{})
+	if err != nil {
+		t.Fatalf("getChartDataForQuery: %v", err)
+	}
+	checkSeries(t, chartData, "newUsersPerYearChart", []string{"2023", "2024"}, map[string][]float64{"Verified": {1, 1}, "Unverified": {0, 1}})
+	checkSeries(t, chartData, "loginsPerYearChart", []string{"2023", "2024"}, map[string][]float64{"Verified": {1, 2}, "Unverified": {0, 0}})
+	checkSeries(t, chartData, "totalUsersPerYearChart", []string{"2023", "2024"}, map[string][]float64{"Verified": {1, 2}, "Unverified": {0, 1}})
+}
+
+func TestWebhookEventsSurviveReload(t *testing.T) {
+	t.Chdir(t.TempDir())
+	user := extract.User{Id: "a", Email: "a@example.com", IsVerified: true, RegisteredDate: date(2024, 1, 1).UnixMilli(), LoginDates: []int64{}, Logins: []extract.Login{}}
+	if err := extract.WriteJson(usersFile, []extract.User{user}); err != nil {
+		t.Fatalf("WriteJson: %v", err)
+	}
+	live := &LiveDashboard{
+		thresholds:  config.DefaultChartsConfig(),
+		options:     extract.Options{ApplicationId: testApplicationId},
+		subscribers: make(map[chan struct{}]bool),
+		eventIds:    make(map[string]bool),
+	}
+	if err := live.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	extractStart := time.Now()
+	login := webhook.Event{Id: "event-1", Type: webhook.EventUserLoginSuccess, CreateInstant: date(2024, 2, 1).UnixMilli(), ApplicationId: testApplicationId, User: fusionauth.User{Id: "a"}}
+	if changed, duplicate := live.applyEvent(login); !changed || duplicate {
+		t.Fatalf("applyEvent = %v, %v, want the login applied", changed, duplicate)
+	}
+	if changed, duplicate := live.applyEvent(login); changed || !duplicate {
+		t.Errorf("applyEvent of a delivered event = %v, %v, want a duplicate", changed, duplicate)
+	}
+
+	if err := extract.WriteJson(usersFile, []extract.User{user}); err != nil {
+		t.Fatalf("WriteJson: %v", err)
+	}
+	if err := live.reloadSince(extractStart); err != nil {
+		t.Fatalf("reloadSince: %v", err)
+	}
+	if !slices.Equal(live.records[0].LoginDates, []int64{login.CreateInstant}) {
+		t.Errorf("login dates after reloading an extract that started before the event = %v, want the login replayed", live.records[0].LoginDates)
+	}
+	if err := live.reloadSince(time.Now()); err != nil {
+		t.Fatalf("reloadSince: %v", err)
+	}
+	if len(live.records[0].LoginDates) != 0 || len(live.events) != 0 {
+		t.Errorf("reloading an extract that started after the event kept %v and %d events", live.records[0].LoginDates, len(live.events))
+	}
+}
+
+func TestWebhookEventsUpdateChangedUsers(t *testing.T) {
+	t.Chdir(t.TempDir())
+	users := []extract.User{
+		{Id: "a", Email: "a@example.com", IsVerified: true, RegisteredDate: date(2024, 1, 1).UnixMilli(), LoginDates: []int64{}, Logins: []extract.Login{}},
+		{Id: "b", Email: "b@other.com", IsVerified: true, RegisteredDate: date(2024, 1, 2).UnixMilli(), LoginDates: []int64{}, Logins: []extract.Login{}},
+	}
+	if err := extract.WriteJson(usersFile, users); err != nil {
+		t.Fatalf("WriteJson: %v", err)
+	}
+	live := &LiveDashboard{
+		thresholds:  config.DefaultChartsConfig(),
+		options:     extract.Options{ApplicationId: testApplicationId},
+		subscribers: make(map[chan struct{}]bool),
+		eventIds:    make(map[string]bool),
+	}
+	if err := live.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	for _, domain := range []string{"example.com", "other.com"} {
+		if _, err := live.current().getChartDataForQuery(url.Values{"domain": {domain}}); err != nil {
+			t.Fatalf("getChartDataForQuery(%s): %v", domain, err)
+		}
+	}
+	previous := live.current()
+	exampleView, otherView := previous.chartDataByView[ViewFilter{Domain: "example.com"}], previous.chartDataByView[ViewFilter{Domain: "other.com"}]
+
+	login := webhook.Event{Type: webhook.EventUserLoginSuccess, CreateInstant: date(2024, 2, 1).UnixMilli(), ApplicationId: testApplicationId, User: fusionauth.User{Id: "a"}}
+	live.applyEvent(login)
+	if err := live.refreshChangedUsers(); err != nil {
+		t.Fatalf("refreshChangedUsers: %v", err)
+	}
+	current := live.current()
+	if current.chartDataByView[ViewFilter{Domain: "other.com"}] != otherView {
+		t.Error("a login by a user outside the other.com view recomputed it")
+	}
+	if current.chartDataByView[ViewFilter{Domain: "example.com"}] == exampleView {
+		t.Error("a login by a user in the example.com view kept its previous charts")
+	}
+	chartData, err := current.getChartDataForQuery(url.Values{})
+	if err != nil {
+		t.Fatalf("getChartDataForQuery: %v", err)
+	}
+	checkSeries(t, chartData, "loginsPerYearChart", []string{"2024"}, map[string][]float64{"Verified": {1}})
+	if len(current.users[0].LoginDates) != 1 || current.users[1].Id != "b" || len(previous.users[0].LoginDates) != 0 {
+		t.Errorf("users after the login = %+v, want only user a changed and the previous dashboard untouched", current.users)
+	}
+
+	registration := fusionauth.Registration{ApplicationId: testApplicationId, InsertInstant: date(2024, 3, 1).UnixMilli()}
+	identities := []fusionauth.Identity{{Primary: true, Type: "email", Value: "c@new.com", Verified: true}}
+	created := webhook.Event{Type: webhook.EventUserRegistrationCreate, User: fusionauth.User{Id: "c", Email: "c@new.com", Identities: identities}, Registration: &registration}
+	live.applyEvent(created)
+	if err := live.refreshChangedUsers(); err != nil {
+		t.Fatalf("refreshChangedUsers: %v", err)
+	}
+	current = live.current()
+	if len(current.users) != 3 || current.users[2].Id != "c" || current.users[2].EmailDomain != "new.com" || current.users[2].BotScore.Id != "c" {
+		t.Fatalf("users after the registration = %+v, want user c added with a domain and bot score", current.users)
+	}
+	if !slices.Contains(current.domains, "new.com") || current.chartDataByView[ViewFilter{Domain: "other.com"}] != otherView {
+		t.Errorf("domains = %v, want new.com added and the other.com view kept", current.domains)
+	}
+}
+
+func TestScenarioPatternsAppearInCharts(t *testing.T) {
+	scenario := mockdata.Scenario{
+		Seed:              7,
+		NumberOfUsers:     600,
+		EmailDomain:       "example.com",
+		RegistrationStart: mockdata.Date{Time: date(2024, 1, 1)},
+		RegistrationEnd:   mockdata.Date{Time: date(2024, 10, 1)},
+		LoginEnd:          mockdata.Date{Time: date(2024, 12, 31)},
+		Applications:      []string{"Web app"},
+		Segments: []mockdata.Segment{
+			{Name: "seasonal", Share: 0.5, LoginIntervalDays: 3, ActiveMonths: []int{11, 12}, Attributes: map[string][]string{"segment": {"seasonal"}}},
+			{Name: "steady", Share: 0.5, LoginIntervalDays: 7, Attributes: map[string][]string{"segment": {"steady"}}},
+		},
+		Campaigns: []mockdata.Campaign{{Name: "launch", Start: mockdata.Date{Time: date(2024, 6, 1)}, Days: 10, Share: 0.3}},
+	}
+	if err := scenario.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	usersJson, err := json.Marshal(mockdata.GenerateScenario(scenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), usersFile)
+	if err := os.WriteFile(path, usersJson, 0644); err != nil {
+		t.Fatal(err)
+	}
+	records, err := extract.ReadUsers(path)
+	if err != nil {
+		t.Fatalf("ReadUsers: %v", err)
+	}
+	users, err := getUsers(records, nil)
+	if err != nil {
+		t.Fatalf("getUsers: %v", err)
+	}
+	users = applySplit(users, splitByAttributePrefix+"segment")
+
+	newUsers := calculateNewUsersPerMonthChart(users, 2024, 2024, time.January, time.December)
+	campaignMonth := getTotal(newUsers, "2024-06")
+	for month := time.January; month <= time.September; month++ {
+		if label := fmt.Sprintf("2024-%02d", month); month != time.June && campaignMonth <= 2*getTotal(newUsers, label) {
+			t.Errorf("new users in the campaign month = %v, want more than twice the %v in %s", campaignMonth, getTotal(newUsers, label), label)
+		}
+	}
+
+	logins := calculateLoginsPerMonthChart(users, 2024, 2024, time.January, time.December)
+	for month := time.January; month <= time.December; month++ {
+		label := fmt.Sprintf("2024-%02d", month)
+		seasonal := getValue(logins, "seasonal", label)
+		if isActive := month >= time.November; isActive != (seasonal > 0) {
+			t.Errorf("seasonal logins in %s = %v, want logins only in November and December", label, seasonal)
+		}
+		if steady := getValue(logins, "steady", label); steady == 0 {
+			t.Errorf("steady logins in %s = 0, want logins every month", label)
+		}
+	}
+}
+
+func getTotal(data ChartData, label string) float64 {
+	total := 0.0
+	for _, series := range data.Series {
+		total += getValue(data, series.Name, label)
+	}
+	return total
+}
+
+func getValue(data ChartData, seriesName string, label string) float64 {
+	index := slices.Index(data.Labels, label)
+	for _, series := range data.Series {
+		if series.Name == seriesName && index != -1 {
+			return series.Data[index]
+		}
+	}
+	return 0
+}
+
+func TestActivityCohortChart(t *testing.T) {
+	now := date(2025, 6, 1)
+	loginsBefore := func(days ...int) []time.Time {
+		return lo.Map(days, func(day int, _ int) time.Time { return now.AddDate(0, 0, -day) })
+	}
+	users := []User{
+		{Series: "Verified", LoginDates: loginsBefore(400)},
+		{Series: "Verified", LoginDates: loginsBefore(365, 10)},
+		{Series: "Verified", LoginDates: loginsBefore(1, 2, 3)},
+		{Series: "Unverified", LoginDates: loginsBefore(1, 2, 3, 4, 5, 6)},
+		{Series: "Unverified"},
+	}
+	chart := calculateActivityCohortChart(users, now, []int{1, 5})
+	checkSeries(t, ChartResult{Charts: []ChartOutput{{Name: "activityCohortChart", Data: chart}}}, "activityCohortChart",
+		[]string{"0", "1", "2-5", "> 5"}, map[string][]float64{"Verified": {1, 0, 2, 0}, "Unverified": {1, 0, 0, 1}})
+}
+
+func TestRequireAdmin(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		token         string
+		remoteAddress string
+		authorization string
+		formToken     string
+		status        int
+	}{
+		{"localhost without a token", "", "127.0.0.1:5000", "", "", http.StatusOK},
+		{"IPv6 localhost without a token", "", "[::1]:5000", "", "", http.StatusOK},
+		{"remote without a token", "", "172.18.0.1:5000", "", "", http.StatusForbidden},
+		{"bearer token", "secret", "172.18.0.1:5000", "Bearer secret", "", http.StatusOK},
+		{"form token", "secret", "172.18.0.1:5000", "", "secret", http.StatusOK},
+		{"wrong token", "secret", "172.18.0.1:5000", "Bearer wrong", "", http.StatusUnauthorized},
+		{"token in the wrong scheme", "secret", "172.18.0.1:5000", "Basic secret", "", http.StatusUnauthorized},
+		{"localhost without the configured token", "secret", "127.0.0.1:5000", "", "", http.StatusUnauthorized},
+	} {
+		handler := requireAdmin(test.token, func(writer http.ResponseWriter, request *http.Request) {})
+		request := httptest.NewRequest(http.MethodPost, "/admin/reload", strings.NewReader(url.Values{"token": {test.formToken}}.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.RemoteAddr = test.remoteAddress
+		if test.authorization != "" {
+			request.Header.Set("Authorization", test.authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		if recorder.Code != test.status {
+			t.Errorf("%s: status %d, want %d", test.name, recorder.Code, test.status)
+		}
+	}
+}
+
+func addTestUser(server *fakefusionauth.Server, email string, isVerified bool, applicationId string, registered time.Time, logins ...time.Time) {
+	user := server.AddUser(fusionauth.User{
+		Email:         email,
+		Verified:      isVerified,
+		InsertInstant: registered.UnixMilli(),
+		Registrations: []fusionauth.Registration{{ApplicationId: applicationId, InsertInstant: registered.UnixMilli()}},
+	})
+	for _, login := range logins {
+		server.AddLogin(fusionauth.LoginRecord{UserId: user.Id, ApplicationId: applicationId, Instant: login.UnixMilli(), IpAddress: "203.0.113.1"})
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.Local)
+}
+
+func checkSeries(t *testing.T, chartData ChartResult, name string, labels []string, series map[string][]float64) {
+	t.Helper()
+	chart, exists := chartData.getChart(name)
+	if !exists {
+		t.Fatalf("chart %s is missing", name)
+	}
+	data := chart.Data.(ChartData)
+	if !slices.Equal(data.Labels, labels) {
+		t.Errorf("%s labels = %v, want %v", name, data.Labels, labels)
+	}
+	for _, got := range data.Series {
+		if want, exists := series[got.Name]; !exists || !slices.Equal(got.Data, want) {
+			t.Errorf("%s series %s = %v, want %v", name, got.Name, got.Data, want)
+		}
+	}
+	if len(data.Series) != len(series) {
+		t.Errorf("%s has %d series, want %d", name, len(data.Series), len(series))
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	ago := func(duration time.Duration) int64 { return now.Add(-duration).UnixMilli() }
+	day := 24 * time.Hour
+	users, err := getUsers([]extract.User{
+		{Id: "a", Email: "a@example.com", IsVerified: true, RegisteredDate: ago(100 * day), LoginDates: []int64{ago(100*day - time.Hour), ago(2 * time.Hour)}},
+		{Id: "b", Email: "b@example.com", IsVerified: true, RegisteredDate: ago(40 * day), LoginDates: []int64{ago(31 * day), ago(20 * day)}},
+		{Id: "c", Email: "c@example.com", RegisteredDate: ago(5 * day), LoginDates: []int64{}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("getUsers: %v", err)
+	}
+	dashboard := newDashboard(users, config.DefaultChartsConfig(), now.Add(-time.Hour))
+	dashboard.loadedAt, dashboard.computeDuration = now.Add(-30*time.Minute), 1500*time.Millisecond
+	live := &LiveDashboard{}
+	live.dashboard.Store(dashboard)
+	live.webhookEvents.Store(5)
+	live.webhookEventsApplied.Store(3)
+
+	var output strings.Builder
+	if err := prometheus.Write(&output, getPrometheusMetrics(live, &ExtractScheduler{}, now)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := `# HELP fusionauth_users Users registered for the application, by email verification.
+# TYPE fusionauth_users gauge
+fusionauth_users{verified="true"} 2
+fusionauth_users{verified="false"} 1
+# HELP fusionauth_active_users Users with a login within the window.
+# TYPE fusionauth_active_users gauge
+fusionauth_active_users{window="1d"} 1
+fusionauth_active_users{window="7d"} 1
+fusionauth_active_users{window="30d"} 2
+# HELP fusionauth_dau_mau_ratio Users active in the past day divided by users active in the past 30 days.
+# TYPE fusionauth_dau_mau_ratio gauge
+fusionauth_dau_mau_ratio 0.5
+# HELP fusionauth_inactive_users Users whose last login was at least this many days ago, from charts.abandonmentDays.
+# TYPE fusionauth_inactive_users gauge
+fusionauth_inactive_users{days="30"} 0
+fusionauth_inactive_users{days="60"} 0
+fusionauth_inactive_users{days="182"} 0
+fusionauth_inactive_users{days="365"} 0
+# HELP fusionauth_users_never_logged_in Users who never logged in.
+# TYPE fusionauth_users_never_logged_in gauge
+fusionauth_users_never_logged_in 1
+# HELP fusionauth_first_login_users Users by time from registration to first login, from charts.frictionDays.
+# TYPE fusionauth_first_login_users gauge
+fusionauth_first_login_users{bucket="<= 1 day"} 1
+fusionauth_first_login_users{bucket="<= 7 days"} 0
+fusionauth_first_login_users{bucket="<= 30 days"} 1
+fusionauth_first_login_users{bucket="> 30 days"} 0
+fusionauth_first_login_users{bucket="Never"} 1
+# HELP fusionauth_suspected_bots Users with a bot score of at least the bot threshold.
+# TYPE fusionauth_suspected_bots gauge
+fusionauth_suspected_bots 0
+# HELP fusionauth_charts_compute_duration_seconds Time taken to compute the default view's charts on the last load.
+# TYPE fusionauth_charts_compute_duration_seconds gauge
+fusionauth_charts_compute_duration_seconds 1.5
+# HELP fusionauth_data_loaded_timestamp_seconds When the data being served was loaded.
+# TYPE fusionauth_data_loaded_timestamp_seconds gauge
+fusionauth_data_loaded_timestamp_seconds 1.7369406e+09
+# HELP fusionauth_data_extracted_timestamp_seconds When users.json was last written.
+# TYPE fusionauth_data_extracted_timestamp_seconds gauge
+fusionauth_data_extracted_timestamp_seconds 1.7369388e+09
+# HELP fusionauth_data_age_seconds Time since users.json was last written.
+# TYPE fusionauth_data_age_seconds gauge
+fusionauth_data_age_seconds 3600
+# HELP fusionauth_webhook_events_total Webhook events received with a valid signature, by whether they changed any user.
+# TYPE fusionauth_webhook_events_total counter
+fusionauth_webhook_events_total{applied="true"} 3
+fusionauth_webhook_events_total{applied="false"} 2
+`
+	if output.String() != want {
+		t.Errorf("/metrics =\n%s\nwant\n%s", output.String(), want)
+	}
+}
@@ -0,0 +1,162 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"app/mail"
+)
+
+const StateFiring = "firing"
+const StateResolved = "resolved"
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+type Notification struct {
+	State  string `json:"state"`
+	Result Result `json:"result"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, notifications []Notification) error
+}
+
+type SmtpNotifier struct {
+	Options mail.Options
+}
+
+type WebhookNotifier struct {
+	Url string
+}
+
+type WebhookPayload struct {
+	Text          string         `json:"text"`
+	Notifications []Notification `json:"notifications"`
+}
+
+type RuleState struct {
+	Result       Result    `json:"result"`
+	LastNotified time.Time `json:"lastNotified"`
+	LastError    string    `json:"lastError,omitempty"`
+	notified     string
+}
+
+type Manager struct {
+	rules           []Rule
+	defaultCooldown time.Duration
+	notifiers       []Notifier
+	mutex           sync.Mutex
+	states          map[string]*RuleState
+}
+
+func NewManager(rules []Rule, defaultCooldown time.Duration, notifiers []Notifier) *Manager {
+	return &Manager{rules: rules, defaultCooldown: defaultCooldown, notifiers: notifiers, states: make(map[string]*RuleState)}
+}
+
+func (m *Manager) Check(ctx context.Context, users []User, now time.Time) ([]Notification, error) {
+	m.mutex.Lock()
+	var notifications []Notification
+	for _, rule := range m.rules {
+		result := Evaluate(rule, users, now)
+		state, exists := m.states[rule.Name]
+		if !exists {
+			state = &RuleState{}
+			m.states[rule.Name] = state
+		}
+		state.Result = result
+		cooldown := m.defaultCooldown
+		if rule.CooldownMinutes > 0 {
+			cooldown = time.Duration(rule.CooldownMinutes) * time.Minute
+		}
+		switch {
+		case result.Firing && (state.notified != StateFiring || now.Sub(state.LastNotified) >= cooldown):
+			notifications = append(notifications, Notification{State: StateFiring, Result: result})
+		case !result.Firing && state.notified == StateFiring:
+			notifications = append(notifications, Notification{State: StateResolved, Result: result})
+		}
+	}
+	m.mutex.Unlock()
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+	var errs []error
+	for _, notifier := range m.notifiers {
+		if err := notifier.Notify(ctx, notifications); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err := errors.Join(errs...)
+	m.mutex.Lock()
+	for _, notification := range notifications {
+		state := m.states[notification.Result.Rule]
+		state.LastError = ""
+		if err != nil {
+			state.LastError = err.Error()
+			continue
+		}
+		state.notified = notification.State
+		state.LastNotified = now
+	}
+	m.mutex.Unlock()
+	return notifications, err
+}
+
+func (m *Manager) States() []RuleState {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	states := []RuleState{}
+	for _, rule := range m.rules {
+		if state, exists := m.states[rule.Name]; exists {
+			states = append(states, *state)
+		}
+	}
+	return states
+}
+
+func (n Notification) String() string {
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(n.State), n.Result.Message)
+}
+
+func (n SmtpNotifier) Notify(ctx context.Context, notifications []Notification) error {
+	subject := notifications[0].String()
+	if len(notifications) > 1 {
+		subject = fmt.Sprintf("%d user chart alerts changed", len(notifications))
+	}
+	lines := make([]string, len(notifications))
+	for index, notification := range notifications {
+		lines[index] = notification.String()
+	}
+	return mail.Send(ctx, n.Options, mail.Message{Subject: subject, Text: strings.Join(lines, "\n") + "\n"})
+}
+
+func (n WebhookNotifier) Notify(ctx context.Context, notifications []Notification) error {
+	lines := make([]string, len(notifications))
+	for index, notification := range notifications {
+		lines[index] = notification.String()
+	}
+	body, err := json.Marshal(WebhookPayload{Text: strings.Join(lines, "\n"), Notifications: notifications})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: status %d", n.Url, response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"app/mail"
+	"app/smtpsink"
+)
+
+var tooManyUsers = Rule{Name: "tooManyUsers", Description: "More than 2 users", Measure: MeasureUsers, Operator: OperatorGreater, Threshold: 2}
+
+type webhookReceiver struct {
+	mutex    sync.Mutex
+	status   int
+	payloads []WebhookPayload
+}
+
+func (r *webhookReceiver) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var payload WebhookPayload
+	json.NewDecoder(request.Body).Decode(&payload)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.payloads = append(r.payloads, payload)
+	writer.WriteHeader(r.status)
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.status = status
+}
+
+func (r *webhookReceiver) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.payloads)
+}
+
+func startSmtpSink(t *testing.T) (*smtpsink.Server, mail.Options) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	sink := smtpsink.New()
+	go sink.Serve(listener)
+	address := listener.Addr().(*net.TCPAddr)
+	return sink, mail.Options{Host: "127.0.0.1", Port: address.Port, From: "charts@example.com", To: []string{"ops@example.com", "oncall@example.com"}}
+}
+
+func getUsers(count int) []User {
+	return make([]User, count)
+}
+
+func TestManagerNotifiesSmtpAndWebhook(t *testing.T) {
+	sink, options := startSmtpSink(t)
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	manager := NewManager([]Rule{tooManyUsers}, time.Hour, []Notifier{SmtpNotifier{Options: options}, WebhookNotifier{Url: server.URL}})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	notifications, err := manager.Check(context.Background(), getUsers(3), now)
+	if err != nil || len(notifications) != 1 || notifications[0].State != StateFiring {
+		t.Fatalf("first check = %+v, %v, want one firing notification", notifications, err)
+	}
+	messages := sink.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("got %d emails, want 1", len(messages))
+	}
+	if messages[0].From != options.From || strings.Join(messages[0].To, ",") != "ops@example.com,oncall@example.com" {
+		t.Errorf("email envelope = %s to %v", messages[0].From, messages[0].To)
+	}
+	if !strings.Contains(messages[0].Data, "\n\n[FIRING] More than 2 users") {
+		t.Errorf("email body has no firing line:\n%s", messages[0].Data)
+	}
+	if receiver.count() != 1 || receiver.payloads[0].Notifications[0].Result.Rule != tooManyUsers.Name || !strings.HasPrefix(receiver.payloads[0].Text, "[FIRING]") {
+		t.Errorf("webhook payloads = %+v", receiver.payloads)
+	}
+
+	if notifications, _ := manager.Check(context.Background(), getUsers(3), now.Add(30*time.Minute)); len(notifications) != 0 {
+		t.Errorf("check within the cool-down notified %+v", notifications)
+	}
+	if notifications, _ := manager.Check(context.Background(), getUsers(3), now.Add(time.Hour)); len(notifications) != 1 {
+		t.Errorf("check after the cool-down notified %+v, want a reminder", notifications)
+	}
+	notifications, err = manager.Check(context.Background(), getUsers(2), now.Add(90*time.Minute))
+	if err != nil || len(notifications) != 1 || notifications[0].State != StateResolved {
+		t.Fatalf("check after resolving = %+v, %v, want one resolved notification", notifications, err)
+	}
+	if messages := sink.Messages(); len(messages) != 3 || !strings.Contains(messages[2].Data, "\n\n[RESOLVED] More than 2 users") {
+		t.Errorf("got %d emails, want the resolved one last", len(messages))
+	}
+	if notifications, _ := manager.Check(context.Background(), getUsers(2), now.Add(2*time.Hour)); len(notifications) != 0 {
+		t.Errorf("check after resolving notified %+v again", notifications)
+	}
+}
+
+func TestManagerRetriesFailedNotifications(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	manager := NewManager([]Rule{tooManyUsers}, time.Hour, []Notifier{WebhookNotifier{Url: server.URL}})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := manager.Check(context.Background(), getUsers(3), now); err == nil {
+		t.Fatal("check with a failing webhook succeeded")
+	}
+	state := manager.States()[0]
+	if !state.LastNotified.IsZero() || !strings.Contains(state.LastError, "status 500") {
+		t.Errorf("state after a failed notification = %+v", state)
+	}
+	receiver.setStatus(http.StatusOK)
+	notifications, err := manager.Check(context.Background(), getUsers(3), now.Add(time.Minute))
+	if err != nil || len(notifications) != 1 || notifications[0].State != StateFiring {
+		t.Fatalf("retry = %+v, %v, want the firing notification again", notifications, err)
+	}
+	if state := manager.States()[0]; !state.LastNotified.Equal(now.Add(time.Minute)) || state.LastError != "" {
+		t.Errorf("state after the retry = %+v", state)
+	}
+
+	receiver.setStatus(http.StatusInternalServerError)
+	manager.Check(context.Background(), getUsers(2), now.Add(2*time.Minute))
+	receiver.setStatus(http.StatusOK)
+	notifications, _ = manager.Check(context.Background(), getUsers(2), now.Add(3*time.Minute))
+	if len(notifications) != 1 || notifications[0].State != StateResolved {
+		t.Errorf("retry of the resolved notification = %+v", notifications)
+	}
+	if receiver.count() != 4 {
+		t.Errorf("webhook received %d requests, want 4", receiver.count())
+	}
+}
+
+func TestSmtpNotifierStopsWhenCancelled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if connection, err := listener.Accept(); err == nil {
+			accepted <- connection
+		}
+	}()
+	options := mail.Options{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port, From: "charts@example.com", To: []string{"ops@example.com"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err = SmtpNotifier{Options: options}.Notify(ctx, []Notification{{State: StateFiring, Result: Result{Rule: "test", Message: "test"}}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("cancelled notification took %s", elapsed)
+	}
+	(<-accepted).Close()
+}
@@ -0,0 +1,167 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	MeasureUsers              = "users"
+	MeasureNewUsers           = "newUsers"
+	MeasureNewVerifiedUsers   = "newVerifiedUsers"
+	MeasureNewUnverifiedUsers = "newUnverifiedUsers"
+	MeasureLogins             = "logins"
+	MeasureActiveUsers        = "activeUsers"
+	MeasureUnverifiedShare    = "unverifiedShare"
+	MeasureSuspectedBotShare  = "suspectedBotShare"
+
+	OperatorLess    = "<"
+	OperatorGreater = ">"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+var windowMeasures = []string{MeasureNewUsers, MeasureNewVerifiedUsers, MeasureNewUnverifiedUsers, MeasureLogins, MeasureActiveUsers}
+var shareMeasures = []string{MeasureUnverifiedShare, MeasureSuspectedBotShare}
+
+type Rule struct {
+	Name            string  `json:"name"`
+	Description     string  `json:"description"`
+	Measure         string  `json:"measure"`
+	WindowDays      int     `json:"windowDays"`
+	BaselineWindows int     `json:"baselineWindows"`
+	Operator        string  `json:"operator"`
+	Threshold       float64 `json:"threshold"`
+	CooldownMinutes int     `json:"cooldownMinutes"`
+}
+
+type User struct {
+	IsVerified     bool
+	IsBot          bool
+	RegisteredDate time.Time
+	Logins         []time.Time
+}
+
+type Result struct {
+	Rule     string    `json:"rule"`
+	Message  string    `json:"message"`
+	Value    float64   `json:"value"`
+	Current  float64   `json:"current"`
+	Baseline float64   `json:"baseline"`
+	NoData   bool      `json:"noData,omitempty"`
+	Firing   bool      `json:"firing"`
+	At       time.Time `json:"at"`
+}
+
+func (r Rule) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("alert %q: %s", r.Name, fmt.Sprintf(format, args...)))
+	}
+	if !namePattern.MatchString(r.Name) {
+		fail("name must start with a letter and contain only letters, digits, '-' and '_'")
+	}
+	isWindowMeasure := slices.Contains(windowMeasures, r.Measure)
+	if !isWindowMeasure && r.Measure != MeasureUsers && !slices.Contains(shareMeasures, r.Measure) {
+		fail("measure %q must be one of %s", r.Measure, strings.Join(slices.Concat([]string{MeasureUsers}, windowMeasures, shareMeasures), ", "))
+	}
+	if isWindowMeasure && r.WindowDays < 1 {
+		fail("measure %q needs windowDays of at least 1", r.Measure)
+	}
+	if !isWindowMeasure && r.WindowDays != 0 {
+		fail("windowDays is only used with %s", strings.Join(windowMeasures, ", "))
+	}
+	if r.BaselineWindows < 0 || (r.BaselineWindows > 0 && !isWindowMeasure) {
+		fail("baselineWindows must be 0, or more with a measure that has a window")
+	}
+	if r.Operator != OperatorLess && r.Operator != OperatorGreater {
+		fail("operator %q must be %q or %q", r.Operator, OperatorLess, OperatorGreater)
+	}
+	if r.CooldownMinutes < 0 {
+		fail("cooldownMinutes must not be negative")
+	}
+	return errors.Join(errs...)
+}
+
+func Evaluate(rule Rule, users []User, now time.Time) Result {
+	result := Result{Rule: rule.Name, At: now, Current: Measure(rule.Measure, rule.WindowDays, users, now)}
+	result.Value = result.Current
+	if rule.BaselineWindows > 0 {
+		window := time.Duration(rule.WindowDays) * 24 * time.Hour
+		total := 0.0
+		for index := 1; index <= rule.BaselineWindows; index++ {
+			total += Measure(rule.Measure, rule.WindowDays, users, now.Add(-time.Duration(index)*window))
+		}
+		result.Baseline = total / float64(rule.BaselineWindows)
+		result.NoData = result.Baseline == 0
+		result.Value = 0
+		if !result.NoData {
+			result.Value = 100 * result.Current / result.Baseline
+		}
+	}
+	switch {
+	case result.NoData:
+	case rule.Operator == OperatorLess:
+		result.Firing = result.Value < rule.Threshold
+	case rule.Operator == OperatorGreater:
+		result.Firing = result.Value > rule.Threshold
+	}
+	result.Message = describe(rule, result)
+	return result
+}
+
+func Measure(measure string, windowDays int, users []User, now time.Time) float64 {
+	windowStart := now.Add(-time.Duration(windowDays) * 24 * time.Hour)
+	isInWindow := func(date time.Time) bool { return date.After(windowStart) && !date.After(now) }
+	count := 0
+	for _, user := range users {
+		switch measure {
+		case MeasureUsers, MeasureUnverifiedShare, MeasureSuspectedBotShare:
+			if measure == MeasureUsers || (measure == MeasureUnverifiedShare && !user.IsVerified) || (measure == MeasureSuspectedBotShare && user.IsBot) {
+				count++
+			}
+		case MeasureNewUsers, MeasureNewVerifiedUsers, MeasureNewUnverifiedUsers:
+			if isInWindow(user.RegisteredDate) && (measure == MeasureNewUsers || user.IsVerified == (measure == MeasureNewVerifiedUsers)) {
+				count++
+			}
+		case MeasureLogins:
+			for _, login := range user.Logins {
+				if isInWindow(login) {
+					count++
+				}
+			}
+		case MeasureActiveUsers:
+			if slices.ContainsFunc(user.Logins, isInWindow) {
+				count++
+			}
+		}
+	}
+	if slices.Contains(shareMeasures, measure) {
+		if len(users) == 0 {
+			return 0
+		}
+		return 100 * float64(count) / float64(len(users))
+	}
+	return float64(count)
+}
+
+func describe(rule Rule, result Result) string {
+	name := rule.Name
+	if rule.Description != "" {
+		name = rule.Description
+	}
+	switch {
+	case result.NoData:
+		return fmt.Sprintf("%s — %s is %g in the last %d days, with no baseline in the previous %d windows", name, rule.Measure, result.Current, rule.WindowDays, rule.BaselineWindows)
+	case rule.BaselineWindows > 0:
+		return fmt.Sprintf("%s — %s is %g in the last %d days, %.0f%% of the %d-window average of %.1f (alert when %s %g%%)", name, rule.Measure, result.Current, rule.WindowDays, result.Value, rule.BaselineWindows, result.Baseline, rule.Operator, rule.Threshold)
+	case slices.Contains(shareMeasures, rule.Measure):
+		return fmt.Sprintf("%s — %s is %.1f%% (alert when %s %g%%)", name, rule.Measure, result.Value, rule.Operator, rule.Threshold)
+	case rule.WindowDays > 0:
+		return fmt.Sprintf("%s — %s is %g in the last %d days (alert when %s %g)", name, rule.Measure, result.Value, rule.WindowDays, rule.Operator, rule.Threshold)
+	}
+	return fmt.Sprintf("%s — %s is %g (alert when %s %g)", name, rule.Measure, result.Value, rule.Operator, rule.Threshold)
+}
@@ -0,0 +1,224 @@
+package chartimage
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	alignLeft = iota
+	alignCenter
+	alignRight
+)
+
+const padding = 10
+const tickCount = 5
+const barFill = 0.8
+const maxShortenedLabels = 12
+
+var white = color.RGBA{255, 255, 255, 255}
+var textColor = color.RGBA{51, 51, 51, 255}
+var gridColor = color.RGBA{225, 225, 225, 255}
+var axisColor = color.RGBA{150, 150, 150, 255}
+
+var seriesColors = map[string]color.RGBA{
+	"Unverified": onWhite(255, 99, 132),
+	"Verified":   onWhite(75, 192, 192),
+}
+
+var seriesPalette = []color.RGBA{
+	onWhite(54, 162, 235), onWhite(255, 159, 64), onWhite(153, 102, 255), onWhite(255, 205, 86),
+	onWhite(75, 192, 192), onWhite(255, 99, 132), onWhite(201, 203, 207), onWhite(46, 204, 113),
+}
+
+type Chart struct {
+	Title      string
+	XAxisTitle string
+	Unit       string
+	Labels     []string
+	Series     []Series
+	Stacked    bool
+}
+
+type Series struct {
+	Name string
+	Data []float64
+}
+
+func (chart Chart) draw(painter painter, width int, height int) {
+	top := drawTitle(painter, chart.Title, width)
+	bottom := height - padding
+	if len(chart.Series) > 1 {
+		bottom = drawLegend(painter, chart, width, bottom)
+	}
+	if chart.XAxisTitle != "" {
+		painter.text(width/2, bottom-glyphHeight, chart.XAxisTitle, 1, textColor, alignCenter)
+		bottom -= glyphHeight + padding/2
+	}
+	bottom -= glyphHeight + padding/2
+	if chart.Unit != "" {
+		painter.text(padding, top, chart.Unit, 1, textColor, alignLeft)
+		top += glyphHeight + padding/2
+	}
+	maximum, step := getScale(chart)
+	left := padding + textWidth(formatNumber(maximum)) + padding/2
+	plotWidth, plotHeight := width-padding-left, bottom-top
+	if plotWidth <= 0 || plotHeight <= 0 {
+		return
+	}
+	for tick := 0; float64(tick)*step <= maximum+step/2; tick++ {
+		value := float64(tick) * step
+		y := bottom - int(math.Round(value/maximum*float64(plotHeight)))
+		painter.rect(left, y, plotWidth, 1, lineColor(value))
+		painter.text(left-padding/2, y-glyphHeight/2, formatNumber(value), 1, textColor, alignRight)
+	}
+	if len(chart.Labels) == 0 {
+		painter.text(left+plotWidth/2, top+plotHeight/2, "No data", 1, textColor, alignCenter)
+		return
+	}
+	groupWidth := float64(plotWidth) / float64(len(chart.Labels))
+	labelEvery := int(math.Ceil(float64(maxLabelWidth(chart.Labels)+padding) / groupWidth))
+	labelLength := math.MaxInt
+	if len(chart.Labels) <= maxShortenedLabels {
+		labelEvery = 1
+		labelLength = max(int(groupWidth-padding/2)/glyphAdvance, 1)
+	}
+	for labelIndex, label := range chart.Labels {
+		groupLeft := float64(left) + groupWidth*float64(labelIndex)
+		if labelIndex%labelEvery == 0 {
+			painter.text(int(groupLeft+groupWidth/2), bottom+padding/2, shorten(label, labelLength), 1, textColor, alignCenter)
+		}
+		barLeft := groupLeft + groupWidth*(1-barFill)/2
+		barWidth := groupWidth * barFill
+		if !chart.Stacked {
+			barWidth /= float64(max(len(chart.Series), 1))
+		}
+		stackBase := 0.0
+		for seriesIndex, series := range chart.Series {
+			if labelIndex >= len(series.Data) || series.Data[labelIndex] <= 0 {
+				continue
+			}
+			value := series.Data[labelIndex]
+			x := barLeft
+			if !chart.Stacked {
+				x += barWidth * float64(seriesIndex)
+				stackBase = 0
+			}
+			y0 := bottom - int(math.Round(stackBase/maximum*float64(plotHeight)))
+			y1 := bottom - int(math.Round((stackBase+value)/maximum*float64(plotHeight)))
+			painter.rect(int(x), y1, max(int(math.Round(x+barWidth))-int(x), 1), max(y0-y1, 1), getSeriesColor(chart, seriesIndex))
+			if chart.Stacked {
+				stackBase += value
+			}
+		}
+	}
+}
+
+func drawTitle(painter painter, title string, width int) int {
+	if title == "" {
+		return padding
+	}
+	titleScale := 2
+	if textWidth(title)*titleScale > width-2*padding {
+		titleScale = 1
+	}
+	painter.text(width/2, padding, shorten(title, (width-2*padding)/glyphAdvance), titleScale, textColor, alignCenter)
+	return padding + glyphHeight*titleScale + padding
+}
+
+func drawLegend(painter painter, chart Chart, width int, bottom int) int {
+	legendWidth := 0
+	for _, series := range chart.Series {
+		legendWidth += glyphHeight + padding/2 + textWidth(series.Name) + padding
+	}
+	x := max((width-legendWidth)/2, padding)
+	y := bottom - glyphHeight
+	for seriesIndex, series := range chart.Series {
+		painter.rect(x, y, glyphHeight, glyphHeight, getSeriesColor(chart, seriesIndex))
+		x += glyphHeight + padding/2
+		painter.text(x, y, series.Name, 1, textColor, alignLeft)
+		x += textWidth(series.Name) + padding
+	}
+	return y - padding
+}
+
+func getScale(chart Chart) (float64, float64) {
+	maximum := 0.0
+	for labelIndex := range chart.Labels {
+		total := 0.0
+		for _, series := range chart.Series {
+			if labelIndex < len(series.Data) {
+				if chart.Stacked {
+					total += max(series.Data[labelIndex], 0)
+				} else {
+					total = max(total, series.Data[labelIndex])
+				}
+			}
+		}
+		maximum = max(maximum, total)
+	}
+	if maximum <= 0 {
+		return 1, 1
+	}
+	rawStep := maximum / tickCount
+	magnitude := math.Pow(10, math.Floor(math.Log10(rawStep)))
+	step := magnitude
+	for _, multiple := range []float64{1, 2, 5, 10} {
+		if multiple*magnitude >= rawStep {
+			step = multiple * magnitude
+			break
+		}
+	}
+	return math.Ceil(maximum/step) * step, step
+}
+
+func getSeriesColor(chart Chart, seriesIndex int) color.RGBA {
+	isVerifiedSplit := true
+	for _, series := range chart.Series {
+		if _, exists := seriesColors[series.Name]; !exists {
+			isVerifiedSplit = false
+		}
+	}
+	if isVerifiedSplit {
+		return seriesColors[chart.Series[seriesIndex].Name]
+	}
+	return seriesPalette[seriesIndex%len(seriesPalette)]
+}
+
+func lineColor(value float64) color.RGBA {
+	if value == 0 {
+		return axisColor
+	}
+	return gridColor
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+func maxLabelWidth(labels []string) int {
+	width := 0
+	for _, label := range labels {
+		width = max(width, textWidth(label))
+	}
+	return width
+}
+
+func shorten(text string, length int) string {
+	characters := []rune(text)
+	if len(characters) <= length {
+		return text
+	}
+	return strings.TrimSpace(string(characters[:max(length-1, 0)])) + "."
+}
+
+func textWidth(text string) int {
+	return len([]rune(text)) * glyphAdvance
+}
+
+func onWhite(red uint8, green uint8, blue uint8) color.RGBA {
+	blend := func(value uint8) uint8 { return uint8(math.Round(float64(value)*0.7 + 255*0.3)) }
+	return color.RGBA{blend(red), blend(green), blend(blue), 255}
+}
@@ -0,0 +1,95 @@
+package chartimage
+
+import (
+	"image/color"
+	"math"
+)
+
+const heatmapMinRowLabelGap = 2
+
+var heatmapColor = color.RGBA{75, 192, 192, 255}
+var heatmapDarkText = color.RGBA{0, 0, 0, 255}
+
+type Heatmap struct {
+	Title      string
+	XAxisTitle string
+	YAxisTitle string
+	Columns    []string
+	Rows       []string
+	Cells      []Cell
+}
+
+type Cell struct {
+	Column string
+	Row    string
+	Value  float64
+}
+
+func (heatmap Heatmap) draw(painter painter, width int, height int) {
+	top := drawTitle(painter, heatmap.Title, width)
+	bottom := height - padding
+	if heatmap.XAxisTitle != "" {
+		painter.text(width/2, bottom-glyphHeight, heatmap.XAxisTitle, 1, textColor, alignCenter)
+		bottom -= glyphHeight + padding/2
+	}
+	bottom -= glyphHeight + padding/2
+	if heatmap.YAxisTitle != "" {
+		painter.text(padding, top, heatmap.YAxisTitle, 1, textColor, alignLeft)
+		top += glyphHeight + padding/2
+	}
+	left := padding + maxLabelWidth(heatmap.Rows) + padding/2
+	plotWidth, plotHeight := width-padding-left, bottom-top
+	if len(heatmap.Columns) == 0 || len(heatmap.Rows) == 0 || plotWidth <= 0 || plotHeight <= 0 {
+		painter.text(width/2, height/2, "No data", 1, textColor, alignCenter)
+		return
+	}
+	columnWidth := float64(plotWidth) / float64(len(heatmap.Columns))
+	rowHeight := float64(plotHeight) / float64(len(heatmap.Rows))
+	columnIndexes := getIndexes(heatmap.Columns)
+	rowIndexes := getIndexes(heatmap.Rows)
+	rowLabelEvery := int(math.Ceil(float64(glyphHeight+heatmapMinRowLabelGap) / rowHeight))
+	for rowIndex, row := range heatmap.Rows {
+		if rowIndex%rowLabelEvery == 0 {
+			y := top + int(rowHeight*float64(rowIndex)+rowHeight/2) - glyphHeight/2
+			painter.text(left-padding/2, y, row, 1, textColor, alignRight)
+		}
+	}
+	columnLabelEvery := int(math.Ceil(float64(maxLabelWidth(heatmap.Columns)+padding) / columnWidth))
+	for columnIndex, column := range heatmap.Columns {
+		if columnIndex%columnLabelEvery == 0 {
+			painter.text(left+int(columnWidth*float64(columnIndex)+columnWidth/2), bottom+padding/2, column, 1, textColor, alignCenter)
+		}
+	}
+	for _, cell := range heatmap.Cells {
+		columnIndex, hasColumn := columnIndexes[cell.Column]
+		rowIndex, hasRow := rowIndexes[cell.Row]
+		if !hasColumn || !hasRow {
+			continue
+		}
+		x0, x1 := left+int(columnWidth*float64(columnIndex)), left+int(columnWidth*float64(columnIndex+1))
+		y0, y1 := top+int(rowHeight*float64(rowIndex)), top+int(rowHeight*float64(rowIndex+1))
+		painter.rect(x0, y0, max(x1-x0, 1), max(y1-y0, 1), getHeatmapColor(cell.Value))
+		label := formatNumber(cell.Value)
+		if textWidth(label) <= x1-x0-2 && glyphHeight <= y1-y0-2 {
+			fill := heatmapDarkText
+			if cell.Value > 50 {
+				fill = white
+			}
+			painter.text((x0+x1)/2, (y0+y1)/2-glyphHeight/2, label, 1, fill, alignCenter)
+		}
+	}
+}
+
+func getHeatmapColor(percent float64) color.RGBA {
+	alpha := math.Min(math.Max(percent/100, 0), 1)
+	blend := func(value uint8) uint8 { return uint8(math.Round(float64(value)*alpha + 255*(1-alpha))) }
+	return color.RGBA{blend(heatmapColor.R), blend(heatmapColor.G), blend(heatmapColor.B), 255}
+}
+
+func getIndexes(labels []string) map[string]int {
+	indexes := make(map[string]int, len(labels))
+	for index, label := range labels {
+		indexes[label] = index
+	}
+	return indexes
+}
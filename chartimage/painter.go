@@ -0,0 +1,103 @@
+package chartimage
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+const svgFontSize = 10
+
+type Drawing interface {
+	draw(painter painter, width int, height int)
+}
+
+type painter interface {
+	rect(x int, y int, width int, height int, fill color.RGBA)
+	text(x int, y int, text string, scale int, fill color.RGBA, align int)
+}
+
+type pngPainter struct {
+	image *image.RGBA
+}
+
+type svgPainter struct {
+	buffer bytes.Buffer
+}
+
+func RenderImage(drawing Drawing, width int, height int) (*image.RGBA, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("image size %dx%d is too small", width, height)
+	}
+	painter := &pngPainter{image: image.NewRGBA(image.Rect(0, 0, width, height))}
+	draw.Draw(painter.image, painter.image.Bounds(), image.NewUniform(white), image.Point{}, draw.Src)
+	drawing.draw(painter, width, height)
+	return painter.image, nil
+}
+
+func RenderPng(drawing Drawing, width int, height int) ([]byte, error) {
+	rendered, err := RenderImage(drawing, width, height)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, rendered); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func RenderSvg(drawing Drawing, width int, height int) ([]byte, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("image size %dx%d is too small", width, height)
+	}
+	painter := &svgPainter{}
+	fmt.Fprintf(&painter.buffer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", width, height, width, height)
+	painter.rect(0, 0, width, height, white)
+	drawing.draw(painter, width, height)
+	painter.buffer.WriteString("</svg>\n")
+	return painter.buffer.Bytes(), nil
+}
+
+func (p *pngPainter) rect(x int, y int, width int, height int, fill color.RGBA) {
+	draw.Draw(p.image, image.Rect(x, y, x+width, y+height), image.NewUniform(fill), image.Point{}, draw.Src)
+}
+
+func (p *pngPainter) text(x int, y int, text string, scale int, fill color.RGBA, align int) {
+	width := textWidth(text) * scale
+	switch align {
+	case alignCenter:
+		x -= width / 2
+	case alignRight:
+		x -= width
+	}
+	for _, character := range text {
+		glyph := getGlyph(character)
+		for column, bits := range glyph {
+			for row := range glyphHeight {
+				if bits&(1<<row) != 0 {
+					p.rect(x+column*scale, y+row*scale, scale, scale, fill)
+				}
+			}
+		}
+		x += glyphAdvance * scale
+	}
+}
+
+func (p *svgPainter) rect(x int, y int, width int, height int, fill color.RGBA) {
+	fmt.Fprintf(&p.buffer, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", x, y, width, height, getHexColor(fill))
+}
+
+func (p *svgPainter) text(x int, y int, text string, scale int, fill color.RGBA, align int) {
+	anchor := map[int]string{alignLeft: "start", alignCenter: "middle", alignRight: "end"}[align]
+	fmt.Fprintf(&p.buffer, `<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="%s" fill="%s">%s</text>`+"\n",
+		x, y+glyphHeight*scale, svgFontSize*scale, anchor, getHexColor(fill), html.EscapeString(text))
+}
+
+func getHexColor(value color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", value.R, value.G, value.B)
+}
@@ -0,0 +1,106 @@
+package chartimage
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func countPixels(rendered image.Image, want color.RGBA) int {
+	count := 0
+	bounds := rendered.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if color.RGBAModel.Convert(rendered.At(x, y)) == want {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestRenderPngRoundTrip(t *testing.T) {
+	chart := Chart{
+		Title:   "New users per month",
+		Unit:    "Users",
+		Labels:  []string{"2025-01", "2025-02", "2025-03"},
+		Series:  []Series{{Name: "Verified", Data: []float64{3, 5, 2}}, {Name: "Unverified", Data: []float64{1, 0, 4}}},
+		Stacked: true,
+	}
+	heatmap := Heatmap{
+		Title:      "Retention",
+		XAxisTitle: "Months since registration",
+		YAxisTitle: "Cohort",
+		Columns:    []string{"0", "1"},
+		Rows:       []string{"2025-01", "2025-02"},
+		Cells:      []Cell{{Column: "0", Row: "2025-01", Value: 100}, {Column: "1", Row: "2025-01", Value: 40}, {Column: "0", Row: "2025-02", Value: 100}},
+	}
+	for _, test := range []struct {
+		name    string
+		drawing Drawing
+		colors  []color.RGBA
+	}{
+		{"chart", chart, []color.RGBA{seriesColors["Verified"], seriesColors["Unverified"], textColor}},
+		{"heatmap", heatmap, []color.RGBA{getHeatmapColor(100), getHeatmapColor(40), textColor}},
+	} {
+		encoded, err := RenderPng(test.drawing, 320, 160)
+		if err != nil {
+			t.Fatalf("%s: RenderPng: %v", test.name, err)
+		}
+		decoded, err := png.Decode(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatalf("%s: the PNG doesn't decode: %v", test.name, err)
+		}
+		if decoded.Bounds() != image.Rect(0, 0, 320, 160) {
+			t.Fatalf("%s: decoded bounds = %v, want 320x160", test.name, decoded.Bounds())
+		}
+		rendered, err := RenderImage(test.drawing, 320, 160)
+		if err != nil {
+			t.Fatalf("%s: RenderImage: %v", test.name, err)
+		}
+		for y := range 160 {
+			for x := range 320 {
+				if got, want := color.RGBAModel.Convert(decoded.At(x, y)), rendered.At(x, y); got != want {
+					t.Fatalf("%s: pixel (%d, %d) = %v after decoding, want %v", test.name, x, y, got, want)
+				}
+			}
+		}
+		if color.RGBAModel.Convert(decoded.At(0, 0)) != white {
+			t.Errorf("%s: background isn't white", test.name)
+		}
+		for _, want := range test.colors {
+			if countPixels(decoded, want) == 0 {
+				t.Errorf("%s: no pixels drawn in %v", test.name, want)
+			}
+		}
+	}
+}
+
+func TestRenderRejectsEmptySizes(t *testing.T) {
+	for _, size := range [][2]int{{0, 100}, {100, 0}, {-1, -1}} {
+		if _, err := RenderPng(Chart{}, size[0], size[1]); err == nil {
+			t.Errorf("RenderPng at %dx%d succeeded, want an error", size[0], size[1])
+		}
+		if _, err := RenderSvg(Chart{}, size[0], size[1]); err == nil {
+			t.Errorf("RenderSvg at %dx%d succeeded, want an error", size[0], size[1])
+		}
+	}
+}
+
+func TestRenderSvg(t *testing.T) {
+	chart := Chart{Title: "Logins <per> month", Labels: []string{"2025-01"}, Series: []Series{{Name: "Verified", Data: []float64{3}}}}
+	svg, err := RenderSvg(chart, 320, 160)
+	if err != nil {
+		t.Fatalf("RenderSvg: %v", err)
+	}
+	text := string(svg)
+	if !strings.HasPrefix(text, `<svg xmlns="http://www.w3.org/2000/svg" width="320" height="160"`) || !strings.HasSuffix(text, "</svg>\n") {
+		t.Errorf("RenderSvg = %s, want a 320x160 SVG document", text)
+	}
+	if !strings.Contains(text, "Logins &lt;per&gt; month") || !strings.Contains(text, getHexColor(seriesColors["Verified"])) {
+		t.Errorf("RenderSvg = %s, want the escaped title and a bar in the series color", text)
+	}
+}
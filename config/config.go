@@ -0,0 +1,266 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"app/alerts"
+	"app/jsonpath"
+	"app/mail"
+	"app/metrics"
+	"app/schedule"
+)
+
+const DefaultFile = "config.json"
+const maxThresholds = 10
+const defaultAlertCooldownMinutes = 360
+const defaultStaticSegment = "all"
+
+var segmentNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+type Config struct {
+	Extract ExtractConfig        `json:"extract"`
+	Metrics []metrics.Definition `json:"metrics"`
+	Charts  ChartsConfig         `json:"charts"`
+	Webhook WebhookConfig        `json:"webhook"`
+	Admin   AdminConfig          `json:"admin"`
+	Alerts  AlertsConfig         `json:"alerts"`
+	Digest  DigestConfig         `json:"digest"`
+	Static  StaticConfig         `json:"static"`
+}
+
+type StaticConfig struct {
+	AssetsDir string          `json:"assetsDir"`
+	Segments  []StaticSegment `json:"segments"`
+}
+
+type StaticSegment struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Query string `json:"query"`
+}
+
+type DigestConfig struct {
+	Schedule     string        `json:"schedule"`
+	Subject      string        `json:"subject"`
+	WindowDays   int           `json:"windowDays"`
+	Charts       []string      `json:"charts"`
+	DashboardUrl string        `json:"dashboardUrl"`
+	Smtp         *mail.Options `json:"smtp"`
+}
+
+type AlertsConfig struct {
+	CooldownMinutes int           `json:"cooldownMinutes"`
+	Smtp            *mail.Options `json:"smtp"`
+	WebhookUrl      string        `json:"webhookUrl"`
+	Rules           []alerts.Rule `json:"rules"`
+}
+
+type WebhookConfig struct {
+	Secret string `json:"secret"`
+}
+
+type AdminConfig struct {
+	Token string `json:"token"`
+}
+
+type ChartsConfig struct {
+	ActivityCohortLogins []int `json:"activityCohortLogins"`
+	FrictionDays         []int `json:"frictionDays"`
+	AbandonmentDays      []int `json:"abandonmentDays"`
+}
+
+func DefaultChartsConfig() ChartsConfig {
+	return ChartsConfig{
+		ActivityCohortLogins: []int{4},
+		FrictionDays:         []int{1, 7, 30},
+		AbandonmentDays:      []int{30, 60, 182, 365},
+	}
+}
+
+func ParseThresholds(text string) ([]int, error) {
+	var thresholds []int
+	for _, part := range strings.Split(text, ",") {
+		threshold, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("threshold %q is not a whole number", strings.TrimSpace(part))
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	return thresholds, ValidateThresholds(thresholds)
+}
+
+func ValidateThresholds(thresholds []int) error {
+	if len(thresholds) == 0 || len(thresholds) > maxThresholds {
+		return fmt.Errorf("between 1 and %d thresholds are needed, got %d", maxThresholds, len(thresholds))
+	}
+	for index, threshold := range thresholds {
+		if threshold < 1 || (index > 0 && threshold <= thresholds[index-1]) {
+			return fmt.Errorf("thresholds must be ascending and at least 1, got %v", thresholds)
+		}
+	}
+	return nil
+}
+
+type ExtractConfig struct {
+	FusionAuthUrl string         `json:"fusionAuthUrl"`
+	ApiKey        string         `json:"apiKey"`
+	ApplicationId string         `json:"applicationId"`
+	Schedule      string         `json:"schedule"`
+	FieldMappings []FieldMapping `json:"fieldMappings"`
+}
+
+func DefaultExtractConfig() ExtractConfig {
+	return ExtractConfig{
+		FusionAuthUrl: "http://fa:9011",
+		ApiKey:        "33052c8a-c283-4e96-9d2a-eb1215c69f8f-not-for-prod",
+		ApplicationId: "e9fdb985-9173-4e01-9d73-ac2d60d1dc8e",
+	}
+}
+
+func DefaultDigestConfig() DigestConfig {
+	return DigestConfig{
+		Subject:    "User charts digest",
+		WindowDays: 7,
+		Charts:     []string{"newUsersPerMonthChart", "loginsPerMonthChart", "activityCohortChart", "suspectedBotsChart"},
+	}
+}
+
+func DefaultStaticConfig() StaticConfig {
+	return StaticConfig{AssetsDir: "assets"}
+}
+
+func DefaultStaticSegment() StaticSegment {
+	return StaticSegment{Name: defaultStaticSegment, Title: "All users"}
+}
+
+type FieldMapping struct {
+	Name   string        `json:"name"`
+	Path   string        `json:"path"`
+	Parsed jsonpath.Path `json:"-"`
+}
+
+func Load(path string) (Config, error) {
+	config := Config{Extract: DefaultExtractConfig(), Charts: DefaultChartsConfig(), Alerts: AlertsConfig{CooldownMinutes: defaultAlertCooldownMinutes}, Digest: DefaultDigestConfig(), Static: DefaultStaticConfig()}
+	fileContent, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(fileContent, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	var errs []error
+	if c.Extract.FusionAuthUrl == "" || c.Extract.ApiKey == "" || c.Extract.ApplicationId == "" {
+		errs = append(errs, errors.New("extract: fusionAuthUrl, apiKey and applicationId can't be empty"))
+	}
+	if c.Extract.Schedule != "" {
+		if _, err := schedule.Parse(c.Extract.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("extract: %w", err))
+		}
+	}
+	names := make(map[string]bool)
+	for index := range c.Extract.FieldMappings {
+		mapping := &c.Extract.FieldMappings[index]
+		prefix := fmt.Sprintf("extract.fieldMappings[%d]", index)
+		if mapping.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", prefix))
+		} else if names[mapping.Name] {
+			errs = append(errs, fmt.Errorf("%s: name %q is used more than once", prefix, mapping.Name))
+		}
+		names[mapping.Name] = true
+		parsed, err := jsonpath.Parse(mapping.Path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", prefix, err))
+		}
+		mapping.Parsed = parsed
+	}
+	metricNames := make(map[string]bool)
+	for index, definition := range c.Metrics {
+		if err := definition.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if metricNames[definition.Name] {
+			errs = append(errs, fmt.Errorf("metrics[%d]: name %q is used more than once", index, definition.Name))
+		}
+		metricNames[definition.Name] = true
+	}
+	if err := ValidateThresholds(c.Charts.ActivityCohortLogins); err != nil {
+		errs = append(errs, fmt.Errorf("charts.activityCohortLogins: %w", err))
+	}
+	if err := ValidateThresholds(c.Charts.FrictionDays); err != nil {
+		errs = append(errs, fmt.Errorf("charts.frictionDays: %w", err))
+	}
+	if err := ValidateThresholds(c.Charts.AbandonmentDays); err != nil {
+		errs = append(errs, fmt.Errorf("charts.abandonmentDays: %w", err))
+	}
+	ruleNames := make(map[string]bool)
+	for index, rule := range c.Alerts.Rules {
+		if err := rule.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if ruleNames[rule.Name] {
+			errs = append(errs, fmt.Errorf("alerts.rules[%d]: name %q is used more than once", index, rule.Name))
+		}
+		ruleNames[rule.Name] = true
+	}
+	if c.Alerts.CooldownMinutes < 1 {
+		errs = append(errs, errors.New("alerts.cooldownMinutes must be at least 1"))
+	}
+	if c.Alerts.Smtp != nil {
+		if err := c.Alerts.Smtp.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("alerts: %w", err))
+		}
+	}
+	if c.Digest.Schedule != "" {
+		if _, err := schedule.Parse(c.Digest.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("digest: %w", err))
+		}
+		if c.Digest.Smtp == nil {
+			errs = append(errs, errors.New("digest: smtp is required with a schedule"))
+		}
+	}
+	if c.Digest.Smtp != nil {
+		if err := c.Digest.Smtp.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("digest: %w", err))
+		}
+	}
+	if c.Digest.Subject == "" || c.Digest.WindowDays < 1 || len(c.Digest.Charts) == 0 {
+		errs = append(errs, errors.New("digest: subject, windowDays of at least 1 and at least one chart are required"))
+	}
+	if c.Static.AssetsDir == "" {
+		errs = append(errs, errors.New("static.assetsDir can't be empty"))
+	}
+	segmentNames := map[string]bool{defaultStaticSegment: true, "index": true}
+	for index, segment := range c.Static.Segments {
+		prefix := fmt.Sprintf("static.segments[%d]", index)
+		if !segmentNamePattern.MatchString(segment.Name) {
+			errs = append(errs, fmt.Errorf("%s: name must start with a letter and contain only letters, digits, '-' and '_'", prefix))
+		} else if segmentNames[segment.Name] {
+			errs = append(errs, fmt.Errorf("%s: name %q is reserved or used more than once", prefix, segment.Name))
+		}
+		segmentNames[segment.Name] = true
+		if segment.Title == "" {
+			errs = append(errs, fmt.Errorf("%s: title is required", prefix))
+		}
+		if _, err := url.ParseQuery(segment.Query); err != nil {
+			errs = append(errs, fmt.Errorf("%s: query: %w", prefix, err))
+		}
+	}
+	return errors.Join(errs...)
+}
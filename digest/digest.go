@@ -0,0 +1,174 @@
+package digest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/alerts"
+	"app/mail"
+)
+
+const pngContentType = "image/png"
+
+var page = template.Must(template.New("digest").Funcs(template.FuncMap{"value": FormatValue, "change": FormatChange}).Parse(`<!DOCTYPE html>
+<html lang="en">
+	<head>
+		<meta charset="UTF-8">
+		<title>{{.Report.Title}}</title>
+	</head>
+	<body style="font-family: sans-serif; color: #333; margin: 20px;">
+		<h1 style="font-size: 22px;">{{.Report.Title}}</h1>
+		<p>{{.Report.WindowDays}} days to {{.Report.GeneratedAt.Format "2006-01-02 15:04 MST"}}, compared with the {{.Report.WindowDays}} days before.{{if not .Report.ExtractedAt.IsZero}} Data extracted {{.Report.ExtractedAt.Format "2006-01-02 15:04 MST"}}.{{end}}</p>
+		<table style="border-collapse: collapse; margin-bottom: 20px;">
+			<tr>
+				<th style="border: 1px solid #ccc; padding: 4px 8px; text-align: left;">Measure</th>
+				<th style="border: 1px solid #ccc; padding: 4px 8px; text-align: right;">Now</th>
+				<th style="border: 1px solid #ccc; padding: 4px 8px; text-align: right;">Before</th>
+				<th style="border: 1px solid #ccc; padding: 4px 8px; text-align: right;">Change</th>
+			</tr>
+			{{range .Report.Kpis}}
+				<tr>
+					<td style="border: 1px solid #ccc; padding: 4px 8px;">{{.Name}}</td>
+					<td style="border: 1px solid #ccc; padding: 4px 8px; text-align: right;">{{value .Current .Unit}}</td>
+					<td style="border: 1px solid #ccc; padding: 4px 8px; text-align: right;">{{if .HasPrevious}}{{value .Previous .Unit}}{{end}}</td>
+					<td style="border: 1px solid #ccc; padding: 4px 8px; text-align: right;">{{if .HasPrevious}}{{change .}}{{end}}</td>
+				</tr>
+			{{end}}
+		</table>
+		{{range .Images}}
+			<img src="{{.Source}}" alt="{{.Title}}" width="{{.Width}}" height="{{.Height}}" style="display: block; max-width: 100%; height: auto; margin-bottom: 20px;">
+		{{end}}
+		{{if .Report.DashboardUrl}}<p><a href="{{.Report.DashboardUrl}}">Open the charts</a></p>{{end}}
+	</body>
+</html>
+`))
+
+type Kpi struct {
+	Name        string
+	Unit        string
+	Current     float64
+	Previous    float64
+	HasPrevious bool
+}
+
+type Chart struct {
+	Name   string
+	Title  string
+	Png    []byte
+	Width  int
+	Height int
+}
+
+type Report struct {
+	Title        string
+	GeneratedAt  time.Time
+	ExtractedAt  time.Time
+	WindowDays   int
+	DashboardUrl string
+	Kpis         []Kpi
+	Charts       []Chart
+}
+
+type image struct {
+	Title  string
+	Source template.URL
+	Width  int
+	Height int
+}
+
+func GetKpis(users []alerts.User, windowDays int, now time.Time) []Kpi {
+	previous := now.Add(-time.Duration(windowDays) * 24 * time.Hour)
+	windowKpi := func(name string, measure string) Kpi {
+		return Kpi{
+			Name:        name,
+			Current:     alerts.Measure(measure, windowDays, users, now),
+			Previous:    alerts.Measure(measure, windowDays, users, previous),
+			HasPrevious: true,
+		}
+	}
+	newUsers := alerts.Measure(alerts.MeasureNewUsers, windowDays, users, now)
+	return []Kpi{
+		{Name: "Users", Current: float64(len(users)), Previous: float64(len(users)) - newUsers, HasPrevious: true},
+		windowKpi("New users", alerts.MeasureNewUsers),
+		windowKpi("New verified users", alerts.MeasureNewVerifiedUsers),
+		windowKpi("Active users", alerts.MeasureActiveUsers),
+		windowKpi("Logins", alerts.MeasureLogins),
+		{Name: "Unverified users", Unit: "%", Current: alerts.Measure(alerts.MeasureUnverifiedShare, 0, users, now)},
+		{Name: "Suspected bots", Unit: "%", Current: alerts.Measure(alerts.MeasureSuspectedBotShare, 0, users, now)},
+	}
+}
+
+func RenderHtml(report Report, getSource func(chart Chart) string) (string, error) {
+	images := make([]image, len(report.Charts))
+	for index, chart := range report.Charts {
+		images[index] = image{Title: chart.Title, Source: template.URL(getSource(chart)), Width: chart.Width, Height: chart.Height}
+	}
+	var buffer bytes.Buffer
+	if err := page.Execute(&buffer, map[string]any{"Report": report, "Images": images}); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func RenderText(report Report) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\n\n%d days to %s, compared with the %d days before.\n\n", report.Title, report.WindowDays, report.GeneratedAt.Format("2006-01-02 15:04 MST"), report.WindowDays)
+	for _, kpi := range report.Kpis {
+		fmt.Fprintf(&text, "%s: %s", kpi.Name, FormatValue(kpi.Current, kpi.Unit))
+		if kpi.HasPrevious {
+			fmt.Fprintf(&text, " (before %s, %s)", FormatValue(kpi.Previous, kpi.Unit), FormatChange(kpi))
+		}
+		text.WriteString("\n")
+	}
+	if report.DashboardUrl != "" {
+		fmt.Fprintf(&text, "\nCharts: %s\n", report.DashboardUrl)
+	}
+	return text.String()
+}
+
+func NewMessage(report Report) (mail.Message, error) {
+	html, err := RenderHtml(report, func(chart Chart) string { return "cid:" + getContentId(chart) })
+	if err != nil {
+		return mail.Message{}, err
+	}
+	message := mail.Message{Subject: report.Title, Text: RenderText(report), Html: html}
+	for _, chart := range report.Charts {
+		message.Inline = append(message.Inline, mail.Inline{ContentId: getContentId(chart), ContentType: pngContentType, Data: chart.Png})
+	}
+	return message, nil
+}
+
+func DataUrl(chart Chart) string {
+	return "data:" + pngContentType + ";base64," + base64.StdEncoding.EncodeToString(chart.Png)
+}
+
+func getContentId(chart Chart) string {
+	return chart.Name + "@digest"
+}
+
+func FormatValue(value float64, unit string) string {
+	if unit == "%" {
+		return fmt.Sprintf("%.1f%%", value)
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+func FormatChange(kpi Kpi) string {
+	if kpi.Previous == 0 {
+		if kpi.Current == 0 {
+			return "0%"
+		}
+		return "new"
+	}
+	change := 100 * (kpi.Current - kpi.Previous) / kpi.Previous
+	if math.Abs(change) < 0.05 {
+		return "0%"
+	}
+	return fmt.Sprintf("%+.1f%%", change)
+}
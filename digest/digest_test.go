@@ -0,0 +1,71 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var report = Report{
+	Title:        "User charts digest",
+	GeneratedAt:  time.Date(2025, 1, 8, 9, 30, 0, 0, time.UTC),
+	WindowDays:   7,
+	DashboardUrl: "https://charts.example.com/",
+	Kpis: []Kpi{
+		{Name: "Users", Current: 120, Previous: 100, HasPrevious: true},
+		{Name: "Logins", Current: 0, Previous: 0, HasPrevious: true},
+		{Name: "Suspected bots", Unit: "%", Current: 2.345},
+	},
+	Charts: []Chart{{Name: "loginsPerMonthChart", Title: "Logins per month", Png: []byte("png"), Width: 640, Height: 320}},
+}
+
+func TestRenderText(t *testing.T) {
+	want := `User charts digest
+
+7 days to 2025-01-08 09:30 UTC, compared with the 7 days before.
+
+Users: 120 (before 100, +20.0%)
+Logins: 0 (before 0, 0%)
+Suspected bots: 2.3%
+
+Charts: https://charts.example.com/
+`
+	if text := RenderText(report); text != want {
+		t.Errorf("RenderText =\n%s\nwant\n%s", text, want)
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	message, err := NewMessage(report)
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+	if len(message.Inline) != 1 || message.Inline[0].ContentId != "loginsPerMonthChart@digest" || message.Inline[0].ContentType != "image/png" || string(message.Inline[0].Data) != "png" {
+		t.Errorf("inline images = %+v, want the chart PNG", message.Inline)
+	}
+	for _, want := range []string{`src="cid:loginsPerMonthChart@digest"`, `alt="Logins per month"`, `<td style="border: 1px solid #ccc; padding: 4px 8px; text-align: right;">&#43;20.0%</td>`, `href="https://charts.example.com/"`} {
+		if !strings.Contains(message.Html, want) {
+			t.Errorf("HTML doesn't contain %s:\n%s", want, message.Html)
+		}
+	}
+	if DataUrl(report.Charts[0]) != "data:image/png;base64,cG5n" {
+		t.Errorf("DataUrl = %s", DataUrl(report.Charts[0]))
+	}
+}
+
+func TestFormatChange(t *testing.T) {
+	for _, test := range []struct {
+		current, previous float64
+		want              string
+	}{
+		{0, 0, "0%"},
+		{5, 0, "new"},
+		{150, 100, "+50.0%"},
+		{50, 100, "-50.0%"},
+		{100.04, 100, "0%"},
+	} {
+		if change := FormatChange(Kpi{Current: test.current, Previous: test.previous}); change != test.want {
+			t.Errorf("FormatChange(%v, %v) = %s, want %s", test.current, test.previous, change, test.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"app/fusionauth"
+	"app/jsonpath"
+)
+
+const incrementalOverlap = 5 * time.Minute
+
+var unverifiedReasons = []string{"Completed", "Implicit", "Pending"}
+
+type Options struct {
+	ApplicationId string
+	FieldMappings []FieldMapping
+}
+
+type FieldMapping struct {
+	Name string
+	Path jsonpath.Path
+}
+
+type User struct {
+	Id             string            `json:"id"`
+	Email          string            `json:"email"`
+	IsVerified     bool              `json:"isVerified"`
+	RegisteredDate int64             `json:"registeredDate"`
+	LoginDates     []int64           `json:"loginDates"`
+	Logins         []Login           `json:"logins"`
+	Attributes     map[string]string `json:"attributes,omitempty"`
+}
+
+type Login struct {
+	Instant          int64  `json:"instant"`
+	IpAddress        string `json:"ipAddress,omitempty"`
+	ApplicationId    string `json:"applicationId,omitempty"`
+	ApplicationName  string `json:"applicationName,omitempty"`
+	IdentityProvider string `json:"identityProvider,omitempty"`
+}
+
+type Result struct {
+	Users       []User
+	FaUsers     []fusionauth.User
+	Start       time.Time
+	Duration    time.Duration
+	NewLogins   int
+	Incremental bool
+}
+
+func Run(ctx context.Context, client *fusionauth.Client, options Options, previous []User, previousStart time.Time) (Result, error) {
+	result := Result{Users: []User{}, Start: time.Now(), Incremental: previous != nil && !previousStart.IsZero()}
+	faUsers, err := client.SearchAllUsers(ctx, "*")
+	if err != nil {
+		return result, err
+	}
+	result.FaUsers = faUsers
+	previousById := make(map[string]User)
+	if result.Incremental {
+		for _, user := range previous {
+			previousById[user.Id] = user
+		}
+	}
+	since := previousStart.Add(-incrementalOverlap).UnixMilli()
+	for _, faUser := range faUsers {
+		user, ok := NewUser(faUser, options)
+		if !ok {
+			continue
+		}
+		previousUser, seenBefore := previousById[user.Id]
+		var logins []fusionauth.LoginRecord
+		if seenBefore {
+			logins, err = client.SearchAllLoginRecordsSince(ctx, user.Id, since)
+		} else {
+			logins, err = client.SearchAllLoginRecords(ctx, user.Id)
+		}
+		if err != nil {
+			return result, err
+		}
+		if err := checkDates(user, logins); err != nil {
+			return result, err
+		}
+		knownLogins := make(map[Login]bool)
+		if seenBefore {
+			user.Logins = slices.Clone(previousUser.Logins)
+			for _, login := range user.Logins {
+				knownLogins[login] = true
+			}
+		}
+		for _, l := range logins {
+			login := NewLogin(l)
+			if !knownLogins[login] {
+				knownLogins[login] = true
+				user.Logins = append(user.Logins, login)
+				result.NewLogins++
+			}
+		}
+		sort.Slice(user.Logins, func(i, j int) bool { return user.Logins[i].Instant < user.Logins[j].Instant })
+		for _, login := range user.Logins {
+			user.LoginDates = append(user.LoginDates, login.Instant)
+		}
+		result.Users = append(result.Users, user)
+	}
+	result.Duration = time.Since(result.Start)
+	return result, nil
+}
+
+func WriteJson(path string, value any) error {
+	content, err := json.MarshalIndent(value, "", "\t")
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func ReadUsers(path string) ([]User, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var users []User
+	if err := json.Unmarshal(content, &users); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return users, nil
+}
+
+func NewUser(faUser fusionauth.User, options Options) (User, bool) {
+	var identity *fusionauth.Identity
+	for _, i := range faUser.Identities {
+		if i.Primary {
+			identity = &i
+			break
+		}
+	}
+	var registration *fusionauth.Registration
+	for _, r := range faUser.Registrations {
+		if r.ApplicationId == options.ApplicationId {
+			registration = &r
+			break
+		}
+	}
+	if identity == nil || registration == nil {
+		return User{}, false
+	}
+	user := User{
+		Id:             faUser.Id,
+		Email:          faUser.Email,
+		IsVerified:     identity.Verified || !slices.Contains(unverifiedReasons, identity.VerifiedReason),
+		RegisteredDate: registration.InsertInstant,
+		LoginDates:     []int64{},
+		Logins:         []Login{},
+		Attributes:     getAttributes(faUser, *registration, options.FieldMappings),
+	}
+	return user, true
+}
+
+func NewLogin(record fusionauth.LoginRecord) Login {
+	return Login{
+		Instant:          record.Instant,
+		IpAddress:        record.IpAddress,
+		ApplicationId:    record.ApplicationId,
+		ApplicationName:  record.ApplicationName,
+		IdentityProvider: getIdentityProvider(record),
+	}
+}
+
+func checkDates(user User, logins []fusionauth.LoginRecord) error {
+	if len(fmt.Sprintf("%d", user.RegisteredDate)) != 13 {
+		return fmt.Errorf("date error: FusionAuth returned registration timestamp that doesn't have 13 digits: %+v", user)
+	}
+	for _, l := range logins {
+		if len(fmt.Sprintf("%d", l.Instant)) != 13 {
+			return fmt.Errorf("date error: FusionAuth returned login timestamp that doesn't have 13 digits: %+v", user)
+		}
+	}
+	return nil
+}
+
+func getAttributes(faUser fusionauth.User, registration fusionauth.Registration, fieldMappings []FieldMapping) map[string]string {
+	attributes := make(map[string]string)
+	document := map[string]any{"user": faUser.Raw, "registration": registration.Raw}
+	for _, mapping := range fieldMappings {
+		value, exists := mapping.Path.Get(document)
+		if !exists {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			attributes[mapping.Name] = v
+		case float64, bool:
+			attributes[mapping.Name] = fmt.Sprintf("%v", v)
+		default:
+			valueJson, _ := json.Marshal(v)
+			attributes[mapping.Name] = string(valueJson)
+		}
+	}
+	return attributes
+}
+
+func getIdentityProvider(login fusionauth.LoginRecord) string {
+	if login.IdentityProviderName != "" {
+		return login.IdentityProviderName
+	}
+	if login.IdentityProviderId != "" {
+		return login.IdentityProviderId
+	}
+	return "Password"
+}
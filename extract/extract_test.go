@@ -0,0 +1,114 @@
+package extract_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"app/extract"
+	"app/fakefusionauth"
+	"app/fusionauth"
+)
+
+const testApplicationId = "test-application"
+
+var previousStart = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func minutesBefore(minutes int) int64 {
+	return previousStart.Add(-time.Duration(minutes) * time.Minute).UnixMilli()
+}
+
+func newServer(t *testing.T) (*fakefusionauth.Server, *fusionauth.Client) {
+	t.Helper()
+	server := fakefusionauth.New(fakefusionauth.Options{})
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return server, fusionauth.NewClient(httpServer.URL, "")
+}
+
+func addUser(server *fakefusionauth.Server, email string) fusionauth.User {
+	return server.AddUser(fusionauth.User{Email: email, InsertInstant: minutesBefore(600), Registrations: []fusionauth.Registration{{ApplicationId: testApplicationId}}})
+}
+
+func login(instant int64, ipAddress string) extract.Login {
+	return extract.Login{Instant: instant, IpAddress: ipAddress, ApplicationId: testApplicationId, IdentityProvider: "Password"}
+}
+
+func TestIncrementalRunOverlap(t *testing.T) {
+	server, client := newServer(t)
+	known := addUser(server, "known@example.com")
+	added := addUser(server, "added@example.com")
+	for _, record := range []fusionauth.LoginRecord{
+		{UserId: known.Id, Instant: minutesBefore(120), IpAddress: "203.0.113.1"},
+		{UserId: known.Id, Instant: minutesBefore(10), IpAddress: "203.0.113.2"},
+		{UserId: known.Id, Instant: minutesBefore(4), IpAddress: "203.0.113.3"},
+		{UserId: known.Id, Instant: minutesBefore(2), IpAddress: "203.0.113.4"},
+		{UserId: known.Id, Instant: previousStart.Add(time.Minute).UnixMilli(), IpAddress: "203.0.113.5"},
+		{UserId: added.Id, Instant: minutesBefore(120), IpAddress: "203.0.113.6"},
+	} {
+		record.ApplicationId = testApplicationId
+		server.AddLogin(record)
+	}
+	options := extract.Options{ApplicationId: testApplicationId}
+	previousUser, _ := extract.NewUser(known, options)
+	previousUser.Logins = []extract.Login{login(minutesBefore(120), "203.0.113.1"), login(minutesBefore(2), "203.0.113.4")}
+	previousUser.LoginDates = []int64{minutesBefore(120), minutesBefore(2)}
+
+	result, err := extract.Run(context.Background(), client, options, []extract.User{previousUser}, previousStart)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !result.Incremental || len(result.Users) != 2 {
+		t.Fatalf("Run = %+v, want an incremental run with 2 users", result)
+	}
+	wantKnown := []extract.Login{
+		login(minutesBefore(120), "203.0.113.1"),
+		login(minutesBefore(4), "203.0.113.3"),
+		login(minutesBefore(2), "203.0.113.4"),
+		login(previousStart.Add(time.Minute).UnixMilli(), "203.0.113.5"),
+	}
+	if !reflect.DeepEqual(result.Users[0].Logins, wantKnown) {
+		t.Errorf("known user's logins = %+v, want the previous logins plus those from the 5 minute overlap on", result.Users[0].Logins)
+	}
+	if want := []int64{minutesBefore(120), minutesBefore(4), minutesBefore(2), previousStart.Add(time.Minute).UnixMilli()}; !reflect.DeepEqual(result.Users[0].LoginDates, want) {
+		t.Errorf("known user's login dates = %v, want %v", result.Users[0].LoginDates, want)
+	}
+	if !reflect.DeepEqual(result.Users[1].Logins, []extract.Login{login(minutesBefore(120), "203.0.113.6")}) {
+		t.Errorf("new user's logins = %+v, want their full history", result.Users[1].Logins)
+	}
+	if result.NewLogins != 3 {
+		t.Errorf("NewLogins = %d, want 3", result.NewLogins)
+	}
+}
+
+func TestLoginsDifferingOnlyByIpAddress(t *testing.T) {
+	server, client := newServer(t)
+	user := addUser(server, "user@example.com")
+	instant := minutesBefore(1)
+	for _, ipAddress := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.2"} {
+		server.AddLogin(fusionauth.LoginRecord{UserId: user.Id, Instant: instant, IpAddress: ipAddress, ApplicationId: testApplicationId})
+	}
+	options := extract.Options{ApplicationId: testApplicationId}
+	want := []extract.Login{login(instant, "203.0.113.1"), login(instant, "203.0.113.2")}
+
+	full, err := extract.Run(context.Background(), client, options, nil, time.Time{})
+	if err != nil {
+		t.Fatalf("full Run: %v", err)
+	}
+	if len(full.Users) != 1 || !reflect.DeepEqual(full.Users[0].Logins, want) || full.NewLogins != 2 {
+		t.Fatalf("full Run = %+v, want both addresses once", full)
+	}
+
+	previousUser := full.Users[0]
+	previousUser.Logins = want[:1]
+	previousUser.LoginDates = []int64{instant}
+	incremental, err := extract.Run(context.Background(), client, options, []extract.User{previousUser}, previousStart)
+	if err != nil {
+		t.Fatalf("incremental Run: %v", err)
+	}
+	if len(incremental.Users) != 1 || !reflect.DeepEqual(incremental.Users[0].Logins, want) || incremental.NewLogins != 1 {
+		t.Errorf("incremental Run = %+v, want the login from the other address added once", incremental)
+	}
+}
@@ -0,0 +1,368 @@
+package fakefusionauth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"app/fusionauth"
+	"app/webhook"
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+type Options struct {
+	ApiKey        string
+	Latency       time.Duration
+	MaxResults    int
+	ErrorEvery    int
+	ErrorStatus   int
+	PathErrors    map[string]int
+	Tenants       []fusionauth.Tenant
+	Applications  []fusionauth.Application
+	WebhookUrl    string
+	WebhookSecret string
+}
+
+type Server struct {
+	options      Options
+	mutex        sync.Mutex
+	users        []fusionauth.User
+	logins       []fusionauth.LoginRecord
+	requestCount int
+	mux          *http.ServeMux
+}
+
+func New(options Options) *Server {
+	if options.ErrorStatus == 0 {
+		options.ErrorStatus = http.StatusInternalServerError
+	}
+	server := &Server{options: options, users: []fusionauth.User{}, logins: []fusionauth.LoginRecord{}, mux: http.NewServeMux()}
+	server.mux.HandleFunc("GET /api/user/search", server.handleUserSearch)
+	server.mux.HandleFunc("GET /api/system/login-record/search", server.handleLoginRecordSearch)
+	server.mux.HandleFunc("POST /api/user/registration", server.handleRegistration)
+	server.mux.HandleFunc("POST /api/user/import", server.handleImport)
+	server.mux.HandleFunc("GET /api/tenant", server.handleTenants)
+	server.mux.HandleFunc("GET /api/application", server.handleApplications)
+	server.mux.HandleFunc("POST /api/login", server.handleLogin)
+	server.mux.HandleFunc("POST /api/user/verify-email", server.handleVerifyEmail)
+	return server
+}
+
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if s.options.Latency > 0 {
+		select {
+		case <-time.After(s.options.Latency):
+		case <-request.Context().Done():
+			return
+		}
+	}
+	if s.options.ApiKey != "" && request.Header.Get("Authorization") != s.options.ApiKey {
+		writeJson(writer, http.StatusUnauthorized, nil)
+		return
+	}
+	s.mutex.Lock()
+	s.requestCount++
+	shouldFail := s.options.ErrorEvery > 0 && s.requestCount%s.options.ErrorEvery == 0
+	s.mutex.Unlock()
+	if status, exists := s.options.PathErrors[request.URL.Path]; exists {
+		writeError(writer, status, "[PathError]", "configured error for "+request.URL.Path)
+		return
+	}
+	if shouldFail {
+		writeError(writer, s.options.ErrorStatus, "[InjectedError]", "configured error every "+strconv.Itoa(s.options.ErrorEvery)+" requests")
+		return
+	}
+	s.mux.ServeHTTP(writer, request)
+}
+
+func (s *Server) AddUser(user fusionauth.User) fusionauth.User {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	user = completeUser(user)
+	s.users = append(s.users, user)
+	return user
+}
+
+func (s *Server) AddLogin(login fusionauth.LoginRecord) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.logins = append(s.logins, login)
+}
+
+func (s *Server) Users() []fusionauth.User {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]fusionauth.User{}, s.users...)
+}
+
+func (s *Server) Logins() []fusionauth.LoginRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]fusionauth.LoginRecord{}, s.logins...)
+}
+
+func (s *Server) RequestCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requestCount
+}
+
+func (s *Server) handleUserSearch(writer http.ResponseWriter, request *http.Request) {
+	queryString := request.URL.Query().Get("queryString")
+	s.mutex.Lock()
+	matches := []fusionauth.User{}
+	for _, user := range s.users {
+		if queryString == "" || queryString == "*" || strings.Contains(user.Email, strings.Trim(queryString, "*")) {
+			matches = append(matches, user)
+		}
+	}
+	s.mutex.Unlock()
+	startRow, numberOfResults := s.getPaging(request)
+	writeJson(writer, http.StatusOK, fusionauth.UserSearchResponse{Total: len(matches), Users: getPage(matches, startRow, numberOfResults)})
+}
+
+func (s *Server) handleLoginRecordSearch(writer http.ResponseWriter, request *http.Request) {
+	userId := request.URL.Query().Get("userId")
+	start, _ := strconv.ParseInt(request.URL.Query().Get("start"), 10, 64)
+	s.mutex.Lock()
+	matches := []fusionauth.LoginRecord{}
+	for _, login := range s.logins {
+		if (userId == "" || login.UserId == userId) && login.Instant >= start {
+			matches = append(matches, login)
+		}
+	}
+	s.mutex.Unlock()
+	startRow, numberOfResults := s.getPaging(request)
+	writeJson(writer, http.StatusOK, fusionauth.LoginRecordSearchResponse{Total: len(matches), Logins: getPage(matches, startRow, numberOfResults)})
+}
+
+func (s *Server) handleRegistration(writer http.ResponseWriter, request *http.Request) {
+	var registrationRequest fusionauth.RegistrationRequest
+	if err := json.NewDecoder(request.Body).Decode(&registrationRequest); err != nil {
+		writeError(writer, http.StatusBadRequest, "[invalidJSON]", err.Error())
+		return
+	}
+	if registrationRequest.User.Email == "" || registrationRequest.Registration.ApplicationId == "" {
+		writeError(writer, http.StatusBadRequest, "[blank]", "user.email and registration.applicationId are required")
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.findUserByEmail(registrationRequest.User.Email) != -1 {
+		writeError(writer, http.StatusBadRequest, "[duplicate]user.email", "a user with email "+registrationRequest.User.Email+" already exists")
+		return
+	}
+	user := registrationRequest.User
+	user.Verified = true
+	user.Registrations = []fusionauth.Registration{registrationRequest.Registration}
+	user = completeUser(user)
+	s.users = append(s.users, user)
+	s.sendEvent(webhook.Event{Type: webhook.EventUserCreate, User: user})
+	s.sendEvent(webhook.Event{Type: webhook.EventUserRegistrationCreate, ApplicationId: user.Registrations[0].ApplicationId, User: user, Registration: &user.Registrations[0]})
+	writeJson(writer, http.StatusOK, fusionauth.RegistrationResponse{User: user, Registration: user.Registrations[0]})
+}
+
+func (s *Server) handleLogin(writer http.ResponseWriter, request *http.Request) {
+	var loginRequest fusionauth.LoginRequest
+	if err := json.NewDecoder(request.Body).Decode(&loginRequest); err != nil {
+		writeError(writer, http.StatusBadRequest, "[invalidJSON]", err.Error())
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	index := s.findUserByEmail(loginRequest.LoginId)
+	if index == -1 {
+		writeJson(writer, http.StatusNotFound, nil)
+		return
+	}
+	user := s.users[index]
+	login := fusionauth.LoginRecord{
+		ApplicationId: loginRequest.ApplicationId,
+		Instant:       time.Now().UnixMilli(),
+		IpAddress:     loginRequest.IpAddress,
+		LoginId:       user.Email,
+		UserId:        user.Id,
+	}
+	for _, application := range s.options.Applications {
+		if application.Id == login.ApplicationId {
+			login.ApplicationName = application.Name
+		}
+	}
+	s.logins = append(s.logins, login)
+	s.sendEvent(webhook.Event{Type: webhook.EventUserLoginSuccess, CreateInstant: login.Instant, ApplicationId: login.ApplicationId, AuthenticationType: "PASSWORD", IpAddress: login.IpAddress, User: user})
+	writeJson(writer, http.StatusOK, fusionauth.LoginResponse{User: user})
+}
+
+func (s *Server) handleVerifyEmail(writer http.ResponseWriter, request *http.Request) {
+	var verifyRequest fusionauth.VerifyEmailRequest
+	if err := json.NewDecoder(request.Body).Decode(&verifyRequest); err != nil {
+		writeError(writer, http.StatusBadRequest, "[invalidJSON]", err.Error())
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	index := slices.IndexFunc(s.users, func(user fusionauth.User) bool { return user.Id == verifyRequest.UserId })
+	if index == -1 {
+		writeJson(writer, http.StatusNotFound, nil)
+		return
+	}
+	user := &s.users[index]
+	user.Verified = true
+	for identityIndex := range user.Identities {
+		if user.Identities[identityIndex].Primary {
+			user.Identities[identityIndex].Verified = true
+			user.Identities[identityIndex].VerifiedReason = "Completed"
+		}
+	}
+	s.sendEvent(webhook.Event{Type: webhook.EventUserEmailVerified, User: *user})
+	writeJson(writer, http.StatusOK, nil)
+}
+
+func (s *Server) handleImport(writer http.ResponseWriter, request *http.Request) {
+	var importRequest fusionauth.ImportRequest
+	if err := json.NewDecoder(request.Body).Decode(&importRequest); err != nil {
+		writeError(writer, http.StatusBadRequest, "[invalidJSON]", err.Error())
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	emails := make(map[string]bool)
+	for _, user := range importRequest.Users {
+		if user.Email == "" || emails[user.Email] || s.findUserByEmail(user.Email) != -1 {
+			writeError(writer, http.StatusBadRequest, "[duplicate]user.email", "duplicate or blank email "+user.Email)
+			return
+		}
+		emails[user.Email] = true
+	}
+	for _, user := range importRequest.Users {
+		s.users = append(s.users, completeUser(user))
+	}
+	writer.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleTenants(writer http.ResponseWriter, request *http.Request) {
+	writeJson(writer, http.StatusOK, fusionauth.TenantResponse{Tenants: s.options.Tenants})
+}
+
+func (s *Server) handleApplications(writer http.ResponseWriter, request *http.Request) {
+	writeJson(writer, http.StatusOK, fusionauth.ApplicationResponse{Applications: s.options.Applications})
+}
+
+func (s *Server) sendEvent(event webhook.Event) {
+	if s.options.WebhookUrl == "" {
+		return
+	}
+	event.Id = newId()
+	if event.CreateInstant == 0 {
+		event.CreateInstant = time.Now().UnixMilli()
+	}
+	event.TenantId = event.User.TenantId
+	body, err := json.Marshal(webhook.Payload{Event: event})
+	if err != nil {
+		return
+	}
+	signature, err := webhook.Sign(body, []byte(s.options.WebhookSecret), time.Now())
+	if err != nil {
+		return
+	}
+	go func() {
+		request, err := http.NewRequest(http.MethodPost, s.options.WebhookUrl, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(webhook.SignatureHeader, signature)
+		response, err := webhookClient.Do(request)
+		if err == nil {
+			response.Body.Close()
+		}
+	}()
+}
+
+func (s *Server) getPaging(request *http.Request) (int, int) {
+	startRow, _ := strconv.Atoi(request.URL.Query().Get("startRow"))
+	numberOfResults, err := strconv.Atoi(request.URL.Query().Get("numberOfResults"))
+	if err != nil || numberOfResults <= 0 {
+		numberOfResults = 25
+	}
+	if s.options.MaxResults > 0 && numberOfResults > s.options.MaxResults {
+		numberOfResults = s.options.MaxResults
+	}
+	return max(startRow, 0), numberOfResults
+}
+
+func (s *Server) findUserByEmail(email string) int {
+	for index, user := range s.users {
+		if strings.EqualFold(user.Email, email) {
+			return index
+		}
+	}
+	return -1
+}
+
+func completeUser(user fusionauth.User) fusionauth.User {
+	now := time.Now().UnixMilli()
+	if user.Id == "" {
+		user.Id = newId()
+	}
+	if user.InsertInstant == 0 {
+		user.InsertInstant = now
+	}
+	user.Password, user.EncryptionScheme, user.Factor, user.Salt = "", "", 0, ""
+	user.Active = true
+	if len(user.Identities) == 0 {
+		user.Identities = []fusionauth.Identity{{Primary: true, Type: "email", Value: user.Email, Verified: user.Verified, VerifiedReason: getVerifiedReason(user.Verified)}}
+	}
+	for index := range user.Registrations {
+		registration := &user.Registrations[index]
+		if registration.Id == "" {
+			registration.Id = newId()
+		}
+		if registration.InsertInstant == 0 {
+			registration.InsertInstant = user.InsertInstant
+		}
+	}
+	return user
+}
+
+func getVerifiedReason(isVerified bool) string {
+	if isVerified {
+		return "Skipped"
+	}
+	return "Pending"
+}
+
+func getPage[T any](items []T, startRow int, numberOfResults int) []T {
+	if startRow >= len(items) {
+		return []T{}
+	}
+	return items[startRow:min(startRow+numberOfResults, len(items))]
+}
+
+func newId() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	bytes[6] = (bytes[6] & 0x0f) | 0x40
+	bytes[8] = (bytes[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:])
+}
+
+func writeError(writer http.ResponseWriter, status int, code string, message string) {
+	writeJson(writer, status, map[string]any{"generalErrors": []map[string]string{{"code": code, "message": message}}})
+}
+
+func writeJson(writer http.ResponseWriter, status int, body any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(writer).Encode(body)
+	}
+}
@@ -0,0 +1,42 @@
+package fakefusionauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLatencyStopsWhenTheRequestIsCancelled(t *testing.T) {
+	server := New(Options{Latency: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/tenant", nil)
+	done := make(chan struct{})
+	go func() {
+		server.ServeHTTP(httptest.NewRecorder(), request)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeHTTP kept sleeping after the request was cancelled")
+	}
+	if server.requestCount != 0 {
+		t.Errorf("cancelled request was counted %d times", server.requestCount)
+	}
+}
+
+func TestLatencyDelaysResponses(t *testing.T) {
+	server := New(Options{Latency: 20 * time.Millisecond})
+	recorder := httptest.NewRecorder()
+	start := time.Now()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/tenant", nil))
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("response took %v, want at least the configured latency", elapsed)
+	}
+	if recorder.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,244 @@
+package fusionauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultTimeout = 30 * time.Second
+const defaultPageSize = 500
+const importEncryptionScheme = "salted-pbkdf2-hmac-sha256"
+const importPasswordFactor = 24000
+
+type Client struct {
+	BaseUrl    string
+	ApiKey     string
+	TenantId   string
+	PageSize   int
+	HttpClient *http.Client
+}
+
+type ApiError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("httpError %d for %s %s: %s", e.StatusCode, e.Method, e.Path, e.Body)
+}
+
+func NewClient(baseUrl string, apiKey string) *Client {
+	return &Client{
+		BaseUrl:    baseUrl,
+		ApiKey:     apiKey,
+		PageSize:   defaultPageSize,
+		HttpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+func (c *Client) SearchUsers(ctx context.Context, queryString string, startRow int, numberOfResults int) (UserSearchResponse, error) {
+	query := url.Values{}
+	query.Set("queryString", queryString)
+	query.Set("startRow", strconv.Itoa(startRow))
+	query.Set("numberOfResults", strconv.Itoa(numberOfResults))
+	var response UserSearchResponse
+	err := c.do(ctx, http.MethodGet, "/api/user/search?"+query.Encode(), nil, &response)
+	return response, err
+}
+
+func (c *Client) SearchAllUsers(ctx context.Context, queryString string) ([]User, error) {
+	users := []User{}
+	for {
+		page, err := c.SearchUsers(ctx, queryString, len(users), c.pageSize())
+		if err != nil {
+			return users, err
+		}
+		users = append(users, page.Users...)
+		if len(page.Users) == 0 || len(users) >= page.Total {
+			return users, nil
+		}
+	}
+}
+
+func (c *Client) SearchLoginRecords(ctx context.Context, userId string, startRow int, numberOfResults int) (LoginRecordSearchResponse, error) {
+	return c.searchLoginRecords(ctx, userId, 0, startRow, numberOfResults)
+}
+
+func (c *Client) SearchAllLoginRecords(ctx context.Context, userId string) ([]LoginRecord, error) {
+	return c.SearchAllLoginRecordsSince(ctx, userId, 0)
+}
+
+func (c *Client) SearchAllLoginRecordsSince(ctx context.Context, userId string, start int64) ([]LoginRecord, error) {
+	logins := []LoginRecord{}
+	for {
+		page, err := c.searchLoginRecords(ctx, userId, start, len(logins), c.pageSize())
+		if err != nil {
+			return logins, err
+		}
+		logins = append(logins, page.Logins...)
+		if len(page.Logins) == 0 || len(logins) >= page.Total {
+			return logins, nil
+		}
+	}
+}
+
+func (c *Client) searchLoginRecords(ctx context.Context, userId string, start int64, startRow int, numberOfResults int) (LoginRecordSearchResponse, error) {
+	query := url.Values{}
+	query.Set("userId", userId)
+	if start > 0 {
+		query.Set("start", strconv.FormatInt(start, 10))
+	}
+	query.Set("startRow", strconv.Itoa(startRow))
+	query.Set("numberOfResults", strconv.Itoa(numberOfResults))
+	var response LoginRecordSearchResponse
+	err := c.do(ctx, http.MethodGet, "/api/system/login-record/search?"+query.Encode(), nil, &response)
+	return response, err
+}
+
+func (c *Client) Register(ctx context.Context, request RegistrationRequest) (RegistrationResponse, error) {
+	var response RegistrationResponse
+	err := c.do(ctx, http.MethodPost, "/api/user/registration", request, &response)
+	return response, err
+}
+
+func (c *Client) ImportUsers(ctx context.Context, request ImportRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/user/import", request, nil)
+}
+
+func (c *Client) ListTenants(ctx context.Context) ([]Tenant, error) {
+	var response TenantResponse
+	err := c.do(ctx, http.MethodGet, "/api/tenant", nil, &response)
+	return response.Tenants, err
+}
+
+func (c *Client) ListApplications(ctx context.Context) ([]Application, error) {
+	var response ApplicationResponse
+	err := c.do(ctx, http.MethodGet, "/api/application", nil, &response)
+	return response.Applications, err
+}
+
+func (c *Client) pageSize() int {
+	if c.PageSize <= 0 {
+		return defaultPageSize
+	}
+	return c.PageSize
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, requestBody any, target any) error {
+	var body io.Reader
+	if requestBody != nil {
+		jsonData, err := json.Marshal(requestBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jsonData)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, c.BaseUrl+path, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", c.ApiKey)
+	request.Header.Set("Content-Type", "application/json")
+	if c.TenantId != "" {
+		request.Header.Set("X-FusionAuth-TenantId", c.TenantId)
+	}
+	httpClient := c.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &ApiError{Method: method, Path: path, StatusCode: response.StatusCode, Body: string(responseBody)}
+	}
+	if target == nil || len(responseBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(responseBody, target)
+}
+
+func SetImportPassword(users []User, password string) error {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	hash, err := pbkdf2.Key(sha256.New, password, salt, importPasswordFactor, 32)
+	if err != nil {
+		return err
+	}
+	for index := range users {
+		users[index].Password = base64.StdEncoding.EncodeToString(hash)
+		users[index].EncryptionScheme = importEncryptionScheme
+		users[index].Factor = importPasswordFactor
+		users[index].Salt = base64.StdEncoding.EncodeToString(salt)
+	}
+	return nil
+}
+
+func (c *Client) ImportUsersInBatches(ctx context.Context, users []User, batchSize int, concurrency int, onBatchDone func(BatchResult)) []BatchResult {
+	batchSize, concurrency = max(batchSize, 1), max(concurrency, 1)
+	var mutex sync.Mutex
+	results := []BatchResult{}
+	addResult := func(batch BatchResult) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		results = append(results, batch)
+		if onBatchDone != nil {
+			onBatchDone(batch)
+		}
+	}
+	batches := make(chan BatchResult)
+	go func() {
+		defer close(batches)
+		for start := 0; start < len(users); start += batchSize {
+			batch := BatchResult{Start: start, End: min(start+batchSize, len(users))}
+			if ctx.Err() != nil {
+				batch.Err = ctx.Err()
+				addResult(batch)
+				continue
+			}
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+				batch.Err = ctx.Err()
+				addResult(batch)
+			}
+		}
+	}()
+	var waitGroup sync.WaitGroup
+	for range concurrency {
+		waitGroup.Go(func() {
+			for batch := range batches {
+				startTime := time.Now()
+				batch.Err = c.ImportUsers(ctx, ImportRequest{Users: users[batch.Start:batch.End]})
+				batch.Duration = time.Since(startTime)
+				addResult(batch)
+			}
+		})
+	}
+	waitGroup.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Start < results[j].Start })
+	return results
+}
@@ -0,0 +1,246 @@
+package fusionauth
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSearchAllUsersPages(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests = append(requests, request.URL.RawQuery)
+		if request.URL.Path != "/api/user/search" || request.Header.Get("Authorization") != "key" {
+			http.Error(writer, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		startRow, _ := strconv.Atoi(request.URL.Query().Get("startRow"))
+		numberOfResults, _ := strconv.Atoi(request.URL.Query().Get("numberOfResults"))
+		response := UserSearchResponse{Total: 5, Users: []User{}}
+		for index := startRow; index < min(startRow+numberOfResults, 5); index++ {
+			response.Users = append(response.Users, User{Id: strconv.Itoa(index), Email: strconv.Itoa(index) + "@example.com"})
+		}
+		json.NewEncoder(writer).Encode(response)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "key")
+	client.PageSize = 2
+	users, err := client.SearchAllUsers(context.Background(), "*")
+	if err != nil {
+		t.Fatalf("SearchAllUsers: %v", err)
+	}
+	if len(users) != 5 || users[0].Id != "0" || users[4].Id != "4" {
+		t.Errorf("got users %+v, want ids 0 to 4", users)
+	}
+	want := []string{
+		"numberOfResults=2&queryString=%2A&startRow=0",
+		"numberOfResults=2&queryString=%2A&startRow=2",
+		"numberOfResults=2&queryString=%2A&startRow=4",
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got requests %v, want %v", requests, want)
+	}
+	for index := range want {
+		if requests[index] != want[index] {
+			t.Errorf("request %d = %s, want %s", index, requests[index], want[index])
+		}
+	}
+}
+
+func TestSearchAllLoginRecordsSincePages(t *testing.T) {
+	instants := []int64{1000, 2000, 3000, 4000, 5000}
+	var starts []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+		starts = append(starts, query.Get("start"))
+		start, _ := strconv.ParseInt(query.Get("start"), 10, 64)
+		startRow, _ := strconv.Atoi(query.Get("startRow"))
+		numberOfResults, _ := strconv.Atoi(query.Get("numberOfResults"))
+		matches := []LoginRecord{}
+		for _, instant := range instants {
+			if instant >= start {
+				matches = append(matches, LoginRecord{UserId: query.Get("userId"), Instant: instant})
+			}
+		}
+		response := LoginRecordSearchResponse{Total: len(matches), Logins: matches[min(startRow, len(matches)):min(startRow+numberOfResults, len(matches))]}
+		json.NewEncoder(writer).Encode(response)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "key")
+	client.PageSize = 2
+	logins, err := client.SearchAllLoginRecordsSince(context.Background(), "user", 2000)
+	if err != nil {
+		t.Fatalf("SearchAllLoginRecordsSince: %v", err)
+	}
+	if len(logins) != 4 || logins[0].Instant != 2000 || logins[3].Instant != 5000 || logins[0].UserId != "user" {
+		t.Errorf("got logins %+v, want instants 2000 to 5000 for user", logins)
+	}
+	if len(starts) != 2 || starts[0] != "2000" || starts[1] != "2000" {
+		t.Errorf("got start parameters %v, want 2 pages from 2000", starts)
+	}
+	starts = nil
+	if _, err := client.SearchAllLoginRecords(context.Background(), "user"); err != nil {
+		t.Fatalf("SearchAllLoginRecords: %v", err)
+	}
+	if len(starts) != 3 {
+		t.Fatalf("SearchAllLoginRecords sent %d requests, want 3 pages", len(starts))
+	}
+	for _, start := range starts {
+		if start != "" {
+			t.Errorf("SearchAllLoginRecords sent start %q, want no start parameter", start)
+		}
+	}
+}
+
+func TestApiErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(status)
+			writer.Write([]byte(`{"generalErrors":[{"code":"[Error]"}]}`))
+		}))
+		_, err := NewClient(server.URL, "key").SearchUsers(context.Background(), "*", 0, 10)
+		server.Close()
+		var apiError *ApiError
+		if !errors.As(err, &apiError) {
+			t.Fatalf("status %d: got error %v, want an *ApiError", status, err)
+		}
+		if apiError.StatusCode != status || apiError.Method != http.MethodGet || apiError.Body != `{"generalErrors":[{"code":"[Error]"}]}` {
+			t.Errorf("status %d: got %+v", status, apiError)
+		}
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		select {
+		case <-release:
+		case <-request.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := NewClient(server.URL, "key").SearchAllUsers(ctx, "*")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("cancelled request took %s", elapsed)
+	}
+}
+
+func TestImportUsersInBatches(t *testing.T) {
+	var mutex sync.Mutex
+	var inFlight, maxInFlight int
+	batches := [][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var importRequest ImportRequest
+		if request.URL.Path != "/api/user/import" || json.NewDecoder(request.Body).Decode(&importRequest) != nil {
+			http.Error(writer, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		emails := []string{}
+		for _, user := range importRequest.Users {
+			emails = append(emails, user.Email)
+		}
+		mutex.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		batches = append(batches, emails)
+		mutex.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+		if slices.Contains(emails, "4@example.com") {
+			http.Error(writer, `{"generalErrors":[{"code":"[duplicate]"}]}`, http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	users := []User{}
+	for index := range 10 {
+		users = append(users, User{Email: strconv.Itoa(index) + "@example.com"})
+	}
+	doneCount := 0
+	results := NewClient(server.URL, "key").ImportUsersInBatches(context.Background(), users, 3, 2, func(BatchResult) { doneCount++ })
+
+	wantBounds := [][2]int{{0, 3}, {3, 6}, {6, 9}, {9, 10}}
+	if len(results) != len(wantBounds) || doneCount != len(wantBounds) {
+		t.Fatalf("got %d results and %d callbacks, want %d", len(results), doneCount, len(wantBounds))
+	}
+	for index, result := range results {
+		if result.Start != wantBounds[index][0] || result.End != wantBounds[index][1] {
+			t.Errorf("batch %d covers users %d to %d, want %d to %d", index, result.Start, result.End, wantBounds[index][0], wantBounds[index][1])
+		}
+		var apiError *ApiError
+		if wantError := index == 1; wantError != errors.As(result.Err, &apiError) {
+			t.Errorf("batch %d error = %v, want an *ApiError: %v", index, result.Err, wantError)
+		}
+	}
+	if maxInFlight > 2 {
+		t.Errorf("%d imports ran at once, want at most 2", maxInFlight)
+	}
+	imported := slices.Concat(batches...)
+	slices.Sort(imported)
+	if len(batches) != 4 || len(slices.Compact(imported)) != 10 {
+		t.Errorf("server received batches %v, want every user exactly once in 4 batches", batches)
+	}
+}
+
+func TestImportUsersInBatchesCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		cancel()
+		select {
+		case <-release:
+		case <-request.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	users := make([]User, 5)
+	results := NewClient(server.URL, "key").ImportUsersInBatches(ctx, users, 1, 1, nil)
+	if len(results) != len(users) {
+		t.Fatalf("got %d results, want one for each of the %d batches", len(results), len(users))
+	}
+	for index, result := range results {
+		if result.Start != index || result.End != index+1 || !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("batch %d = %+v, want users %d to %d failed with context.Canceled", index, result, index, index+1)
+		}
+	}
+}
+
+func TestSetImportPassword(t *testing.T) {
+	users := make([]User, 2)
+	if err := SetImportPassword(users, "password"); err != nil {
+		t.Fatalf("SetImportPassword: %v", err)
+	}
+	salt, err := base64.StdEncoding.DecodeString(users[0].Salt)
+	if err != nil {
+		t.Fatalf("salt %q isn't base64: %v", users[0].Salt, err)
+	}
+	hash, err := pbkdf2.Key(sha256.New, "password", salt, users[0].Factor, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, user := range users {
+		if user.EncryptionScheme != "salted-pbkdf2-hmac-sha256" || user.Factor != 24000 || user.Salt != users[0].Salt || user.Password != base64.StdEncoding.EncodeToString(hash) {
+			t.Errorf("user %d = %+v, want a salted PBKDF2 hash of the password", index, user)
+		}
+	}
+}
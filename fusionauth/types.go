@@ -0,0 +1,134 @@
+package fusionauth
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Identity struct {
+	Primary        bool   `json:"primary"`
+	Type           string `json:"type,omitempty"`
+	Value          string `json:"value,omitempty"`
+	Verified       bool   `json:"verified"`
+	VerifiedReason string `json:"verifiedReason,omitempty"`
+}
+
+type Registration struct {
+	Id            string         `json:"id,omitempty"`
+	ApplicationId string         `json:"applicationId"`
+	InsertInstant int64          `json:"insertInstant,omitempty"`
+	Verified      bool           `json:"verified,omitempty"`
+	Data          map[string]any `json:"data,omitempty"`
+	Raw           map[string]any `json:"-"`
+}
+
+type User struct {
+	Id               string         `json:"id,omitempty"`
+	Email            string         `json:"email"`
+	Password         string         `json:"password,omitempty"`
+	EncryptionScheme string         `json:"encryptionScheme,omitempty"`
+	Factor           int            `json:"factor,omitempty"`
+	Salt             string         `json:"salt,omitempty"`
+	Active           bool           `json:"active,omitempty"`
+	Verified         bool           `json:"verified,omitempty"`
+	InsertInstant    int64          `json:"insertInstant,omitempty"`
+	TenantId         string         `json:"tenantId,omitempty"`
+	Identities       []Identity     `json:"identities,omitempty"`
+	Registrations    []Registration `json:"registrations,omitempty"`
+	Data             map[string]any `json:"data,omitempty"`
+	Raw              map[string]any `json:"-"`
+}
+
+func (u *User) UnmarshalJSON(data []byte) error {
+	type plainUser User
+	if err := json.Unmarshal(data, (*plainUser)(u)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &u.Raw)
+}
+
+func (r *Registration) UnmarshalJSON(data []byte) error {
+	type plainRegistration Registration
+	if err := json.Unmarshal(data, (*plainRegistration)(r)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &r.Raw)
+}
+
+type UserSearchResponse struct {
+	Total int    `json:"total"`
+	Users []User `json:"users"`
+}
+
+type LoginRecord struct {
+	ApplicationId        string `json:"applicationId,omitempty"`
+	ApplicationName      string `json:"applicationName,omitempty"`
+	IdentityProviderId   string `json:"identityProviderId,omitempty"`
+	IdentityProviderName string `json:"identityProviderName,omitempty"`
+	Instant              int64  `json:"instant"`
+	IpAddress            string `json:"ipAddress,omitempty"`
+	LoginId              string `json:"loginId,omitempty"`
+	UserId               string `json:"userId,omitempty"`
+}
+
+type LoginRecordSearchResponse struct {
+	Total  int           `json:"total"`
+	Logins []LoginRecord `json:"logins"`
+}
+
+type RegistrationRequest struct {
+	User         User         `json:"user"`
+	Registration Registration `json:"registration"`
+}
+
+type RegistrationResponse struct {
+	User         User         `json:"user"`
+	Registration Registration `json:"registration"`
+}
+
+type LoginRequest struct {
+	LoginId       string `json:"loginId"`
+	Password      string `json:"password,omitempty"`
+	ApplicationId string `json:"applicationId,omitempty"`
+	IpAddress     string `json:"ipAddress,omitempty"`
+}
+
+type LoginResponse struct {
+	User User `json:"user"`
+}
+
+type VerifyEmailRequest struct {
+	UserId string `json:"userId"`
+}
+
+type ImportRequest struct {
+	Users                 []User `json:"users"`
+	ValidateDbConstraints bool   `json:"validateDbConstraints,omitempty"`
+}
+
+type BatchResult struct {
+	Start    int
+	End      int
+	Duration time.Duration
+	Err      error
+}
+
+type Tenant struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type TenantResponse struct {
+	Tenants []Tenant `json:"tenants"`
+}
+
+type Application struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	TenantId string `json:"tenantId"`
+	Active   bool   `json:"active"`
+}
+
+type ApplicationResponse struct {
+	Applications []Application `json:"applications"`
+}
@@ -0,0 +1,356 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+const dataSectionSeparatorSize = 16
+
+type Reader struct {
+	buffer       []byte
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	treeSize     uint
+	ipv4Start    uint
+	DatabaseType string
+}
+
+type Location struct {
+	CountryCode      string
+	CountryName      string
+	RegionCode       string
+	RegionName       string
+	Latitude         float64
+	Longitude        float64
+	AccuracyRadiusKm float64
+	HasCoordinates   bool
+}
+
+func Open(path string) (*Reader, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(buffer)
+}
+
+func New(buffer []byte) (*Reader, error) {
+	markerIndex := bytes.LastIndex(buffer, metadataMarker)
+	if markerIndex == -1 {
+		return nil, errors.New("geoip: metadata marker not found, not a MaxMind DB file")
+	}
+	metadataStart := markerIndex + len(metadataMarker)
+	metadataDecoder := decoder{buffer: buffer[metadataStart:]}
+	metadataValue, _, err := metadataDecoder.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("geoip: reading metadata: %w", err)
+	}
+	metadata, isMap := metadataValue.(map[string]any)
+	if !isMap {
+		return nil, errors.New("geoip: metadata is not a map")
+	}
+	reader := &Reader{buffer: buffer}
+	reader.nodeCount = toUint(metadata["node_count"])
+	reader.recordSize = toUint(metadata["record_size"])
+	reader.ipVersion = toUint(metadata["ip_version"])
+	reader.DatabaseType, _ = metadata["database_type"].(string)
+	if reader.recordSize != 24 && reader.recordSize != 28 && reader.recordSize != 32 {
+		return nil, fmt.Errorf("geoip: unsupported record size %d", reader.recordSize)
+	}
+	reader.treeSize = reader.recordSize * 2 / 8 * reader.nodeCount
+	if reader.treeSize+dataSectionSeparatorSize > uint(markerIndex) {
+		return nil, errors.New("geoip: search tree is larger than the file")
+	}
+	if reader.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < reader.nodeCount; i++ {
+			node = reader.readNode(node, 0)
+		}
+		reader.ipv4Start = node
+	}
+	return reader, nil
+}
+
+func (r *Reader) LookupValue(address netip.Addr) (any, error) {
+	address = address.Unmap()
+	ipBytes := address.AsSlice()
+	node := uint(0)
+	if address.Is4() {
+		node = r.ipv4Start
+	} else if r.ipVersion == 4 {
+		return nil, nil
+	}
+	bitCount := len(ipBytes) * 8
+	for i := 0; i < bitCount && node < r.nodeCount; i++ {
+		bit := (ipBytes[i>>3] >> (7 - uint(i%8))) & 1
+		node = r.readNode(node, uint(bit))
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, errors.New("geoip: invalid search tree")
+	}
+	offset := node - r.nodeCount - dataSectionSeparatorSize
+	dataDecoder := decoder{buffer: r.buffer[r.treeSize+dataSectionSeparatorSize:]}
+	value, _, err := dataDecoder.decode(offset)
+	return value, err
+}
+
+func (r *Reader) Lookup(ipAddress string) (Location, bool) {
+	address, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return Location{}, false
+	}
+	value, err := r.LookupValue(address)
+	record, isMap := value.(map[string]any)
+	if err != nil || !isMap {
+		return Location{}, false
+	}
+	location := Location{}
+	country, _ := record["country"].(map[string]any)
+	if country == nil {
+		country, _ = record["registered_country"].(map[string]any)
+	}
+	location.CountryCode, location.CountryName = getCodeAndName(country)
+	if subdivisions, isArray := record["subdivisions"].([]any); isArray && len(subdivisions) > 0 {
+		subdivision, _ := subdivisions[0].(map[string]any)
+		location.RegionCode, location.RegionName = getCodeAndName(subdivision)
+	}
+	if coordinates, isMap := record["location"].(map[string]any); isMap {
+		latitude, hasLatitude := coordinates["latitude"].(float64)
+		longitude, hasLongitude := coordinates["longitude"].(float64)
+		location.Latitude, location.Longitude = latitude, longitude
+		location.AccuracyRadiusKm = float64(toUint(coordinates["accuracy_radius"]))
+		location.HasCoordinates = hasLatitude && hasLongitude
+	}
+	return location, location.CountryCode != ""
+}
+
+func (r *Reader) readNode(node uint, bit uint) uint {
+	offset := node * r.recordSize * 2 / 8
+	b := r.buffer[offset:]
+	switch r.recordSize {
+	case 24:
+		if bit == 0 {
+			return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3])<<16 | uint(b[4])<<8 | uint(b[5])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		if bit == 0 {
+			return uint(binary.BigEndian.Uint32(b[0:4]))
+		}
+		return uint(binary.BigEndian.Uint32(b[4:8]))
+	}
+}
+
+func getCodeAndName(entry map[string]any) (string, string) {
+	if entry == nil {
+		return "", ""
+	}
+	code, _ := entry["iso_code"].(string)
+	name := code
+	if names, isMap := entry["names"].(map[string]any); isMap {
+		if englishName, isString := names["en"].(string); isString {
+			name = englishName
+		}
+	}
+	return code, name
+}
+
+func toUint(value any) uint {
+	switch v := value.(type) {
+	case uint64:
+		return uint(v)
+	case uint32:
+		return uint(v)
+	case uint16:
+		return uint(v)
+	case int32:
+		return uint(v)
+	}
+	return 0
+}
+
+type decoder struct {
+	buffer []byte
+}
+
+const (
+	typeExtended  = 0
+	typePointer   = 1
+	typeString    = 2
+	typeDouble    = 3
+	typeBytes     = 4
+	typeUint16    = 5
+	typeUint32    = 6
+	typeMap       = 7
+	typeInt32     = 8
+	typeUint64    = 9
+	typeUint128   = 10
+	typeArray     = 11
+	typeContainer = 12
+	typeEndMarker = 13
+	typeBoolean   = 14
+	typeFloat     = 15
+)
+
+func (d decoder) decode(offset uint) (any, uint, error) {
+	if offset >= uint(len(d.buffer)) {
+		return nil, 0, errors.New("geoip: data offset out of range")
+	}
+	control := d.buffer[offset]
+	offset++
+	dataType := uint(control >> 5)
+	if dataType == typePointer {
+		pointer, newOffset, err := d.readPointer(control, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer)
+		return value, newOffset, err
+	}
+	if dataType == typeExtended {
+		if offset >= uint(len(d.buffer)) {
+			return nil, 0, errors.New("geoip: truncated extended type")
+		}
+		dataType = 7 + uint(d.buffer[offset])
+		offset++
+	}
+	size, offset, err := d.readSize(control, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch dataType {
+	case typeMap:
+		result := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			key, newOffset, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			keyString, isString := key.(string)
+			if !isString {
+				return nil, 0, errors.New("geoip: map key is not a string")
+			}
+			value, newOffset, err := d.decode(newOffset)
+			if err != nil {
+				return nil, 0, err
+			}
+			result[keyString] = value
+			offset = newOffset
+		}
+		return result, offset, nil
+	case typeArray:
+		result := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, newOffset, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			result = append(result, value)
+			offset = newOffset
+		}
+		return result, offset, nil
+	case typeBoolean:
+		return size != 0, offset, nil
+	case typeEndMarker, typeContainer:
+		return nil, offset, nil
+	}
+	if offset+size > uint(len(d.buffer)) {
+		return nil, 0, errors.New("geoip: value runs past the end of the data section")
+	}
+	raw := d.buffer[offset : offset+size]
+	offset += size
+	switch dataType {
+	case typeString:
+		return string(raw), offset, nil
+	case typeBytes:
+		return append([]byte{}, raw...), offset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("geoip: invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), offset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("geoip: invalid float size")
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(raw)), offset, nil
+	case typeUint16, typeUint32, typeUint64:
+		value := uint64(0)
+		for _, b := range raw {
+			value = value<<8 | uint64(b)
+		}
+		return value, offset, nil
+	case typeInt32:
+		value := uint32(0)
+		for _, b := range raw {
+			value = value<<8 | uint32(b)
+		}
+		return int32(value), offset, nil
+	case typeUint128:
+		return append([]byte{}, raw...), offset, nil
+	}
+	return nil, 0, fmt.Errorf("geoip: unknown data type %d", dataType)
+}
+
+func (d decoder) readSize(control byte, offset uint) (uint, uint, error) {
+	size := uint(control & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+	extraBytes := size - 28
+	if offset+extraBytes > uint(len(d.buffer)) {
+		return 0, 0, errors.New("geoip: truncated size")
+	}
+	value := uint(0)
+	for _, b := range d.buffer[offset : offset+extraBytes] {
+		value = value<<8 | uint(b)
+	}
+	switch size {
+	case 29:
+		size = 29 + value
+	case 30:
+		size = 285 + value
+	default:
+		size = 65821 + value
+	}
+	return size, offset + extraBytes, nil
+}
+
+func (d decoder) readPointer(control byte, offset uint) (uint, uint, error) {
+	pointerSize := uint((control>>3)&0x3) + 1
+	if offset+pointerSize > uint(len(d.buffer)) {
+		return 0, 0, errors.New("geoip: truncated pointer")
+	}
+	raw := d.buffer[offset : offset+pointerSize]
+	value := uint(0)
+	if pointerSize != 4 {
+		value = uint(control & 0x7)
+	}
+	for _, b := range raw {
+		value = value<<8 | uint(b)
+	}
+	switch pointerSize {
+	case 2:
+		value += 2048
+	case 3:
+		value += 526336
+	}
+	return value, offset + pointerSize, nil
+}
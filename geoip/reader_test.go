@@ -0,0 +1,236 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type fixtureEncoder struct {
+	bytes.Buffer
+}
+
+func (e *fixtureEncoder) control(dataType int, size int) {
+	sizeBits, extra := size, []byte{}
+	switch {
+	case size >= 65821:
+		sizeBits, extra = 31, binary.BigEndian.AppendUint32(nil, uint32(size-65821))[1:]
+	case size >= 285:
+		sizeBits, extra = 30, binary.BigEndian.AppendUint16(nil, uint16(size-285))
+	case size >= 29:
+		sizeBits, extra = 29, []byte{byte(size - 29)}
+	}
+	if dataType > 7 {
+		e.WriteByte(byte(sizeBits))
+		e.WriteByte(byte(dataType - 7))
+	} else {
+		e.WriteByte(byte(dataType<<5 | sizeBits))
+	}
+	e.Write(extra)
+}
+
+func (e *fixtureEncoder) encode(value any) {
+	switch v := value.(type) {
+	case string:
+		e.control(typeString, len(v))
+		e.WriteString(v)
+	case float64:
+		e.control(typeDouble, 8)
+		binary.Write(e, binary.BigEndian, math.Float64bits(v))
+	case uint16:
+		e.control(typeUint16, 2)
+		binary.Write(e, binary.BigEndian, v)
+	case uint32:
+		e.control(typeUint32, 4)
+		binary.Write(e, binary.BigEndian, v)
+	case pointer:
+		e.WriteByte(byte(typePointer<<5 | int(v)>>8&0x7))
+		e.WriteByte(byte(v))
+	case []any:
+		e.control(typeArray, len(v))
+		for _, item := range v {
+			e.encode(item)
+		}
+	case map[string]any:
+		e.control(typeMap, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			e.encode(key)
+			e.encode(v[key])
+		}
+	}
+}
+
+type pointer uint
+
+type fixtureNetwork struct {
+	prefix netip.Prefix
+	record any
+}
+
+func buildFixture(t *testing.T, ipVersion int, recordSize int, networks []fixtureNetwork) []byte {
+	t.Helper()
+	data := &fixtureEncoder{}
+	data.encode(map[string]any{"iso_code": "CN", "names": map[string]any{"en": "China"}})
+	const emptyRecord = -1
+	type node [2]int
+	nodes := []node{{emptyRecord, emptyRecord}}
+	dataRecords := map[[2]int]uint{}
+	for _, network := range networks {
+		offset := uint(data.Len())
+		data.encode(network.record)
+		address := network.prefix.Addr()
+		bits := network.prefix.Bits()
+		if address.Is4() && ipVersion == 6 {
+			address = netip.AddrFrom16([16]byte(append(make([]byte, 12), network.prefix.Addr().AsSlice()...)))
+			bits += 96
+		}
+		ipBytes := address.AsSlice()
+		current := 0
+		for i := 0; i < bits; i++ {
+			bit := int(ipBytes[i>>3]>>(7-uint(i%8))) & 1
+			if i == bits-1 {
+				dataRecords[[2]int{current, bit}] = offset
+				break
+			}
+			if nodes[current][bit] == emptyRecord {
+				nodes = append(nodes, node{emptyRecord, emptyRecord})
+				nodes[current][bit] = len(nodes) - 1
+			}
+			current = nodes[current][bit]
+		}
+	}
+	nodeCount := uint(len(nodes))
+	tree := []byte{}
+	for index, n := range nodes {
+		records := [2]uint{}
+		for bit := range 2 {
+			switch offset, isData := dataRecords[[2]int{index, bit}]; {
+			case isData:
+				records[bit] = nodeCount + dataSectionSeparatorSize + offset
+			case n[bit] == emptyRecord:
+				records[bit] = nodeCount
+			default:
+				records[bit] = uint(n[bit])
+			}
+		}
+		switch recordSize {
+		case 24:
+			tree = append(tree, byte(records[0]>>16), byte(records[0]>>8), byte(records[0]), byte(records[1]>>16), byte(records[1]>>8), byte(records[1]))
+		case 28:
+			tree = append(tree, byte(records[0]>>16), byte(records[0]>>8), byte(records[0]), byte(records[0]>>24<<4|records[1]>>24), byte(records[1]>>16), byte(records[1]>>8), byte(records[1]))
+		default:
+			tree = binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(tree, uint32(records[0])), uint32(records[1]))
+		}
+	}
+	metadata := &fixtureEncoder{}
+	metadata.encode(map[string]any{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(recordSize),
+		"ip_version":    uint16(ipVersion),
+		"database_type": "Test-City",
+		"languages":     []any{"en"},
+	})
+	file := append(tree, make([]byte, dataSectionSeparatorSize)...)
+	file = append(file, data.Bytes()...)
+	file = append(file, metadataMarker...)
+	return append(file, metadata.Bytes()...)
+}
+
+func TestLookup(t *testing.T) {
+	networks := []fixtureNetwork{
+		{netip.MustParsePrefix("81.2.69.0/24"), map[string]any{
+			"country":      map[string]any{"iso_code": "GB", "names": map[string]any{"en": "United Kingdom", "de": "Vereinigtes Königreich"}},
+			"subdivisions": []any{map[string]any{"iso_code": "ENG", "names": map[string]any{"en": "England"}}},
+			"location":     map[string]any{"latitude": 51.5142, "longitude": -0.0931, "accuracy_radius": uint16(100)},
+		}},
+		{netip.MustParsePrefix("175.16.199.0/24"), map[string]any{"registered_country": pointer(0)}},
+		{netip.MustParsePrefix("89.160.20.128/25"), map[string]any{"country": map[string]any{"iso_code": "SE", "names": map[string]any{"en": strings.Repeat("Sweden ", 50)}}}},
+	}
+	v6Networks := append(networks, fixtureNetwork{netip.MustParsePrefix("2001:db8::/32"), map[string]any{"country": map[string]any{"iso_code": "DE"}}})
+	gb := Location{CountryCode: "GB", CountryName: "United Kingdom", RegionCode: "ENG", RegionName: "England", Latitude: 51.5142, Longitude: -0.0931, AccuracyRadiusKm: 100, HasCoordinates: true}
+	for _, recordSize := range []int{24, 28, 32} {
+		for _, ipVersion := range []int{4, 6} {
+			fixtureNetworks := networks
+			if ipVersion == 6 {
+				fixtureNetworks = v6Networks
+			}
+			reader, err := New(buildFixture(t, ipVersion, recordSize, fixtureNetworks))
+			if err != nil {
+				t.Fatalf("IPv%d, %d-bit records: New: %v", ipVersion, recordSize, err)
+			}
+			if reader.DatabaseType != "Test-City" {
+				t.Errorf("IPv%d, %d-bit records: DatabaseType = %q", ipVersion, recordSize, reader.DatabaseType)
+			}
+			for _, test := range []struct {
+				address  string
+				location Location
+				found    bool
+			}{
+				{"81.2.69.160", gb, true},
+				{"81.2.69.0", gb, true},
+				{"::ffff:81.2.69.1", gb, true},
+				{"175.16.199.5", Location{CountryCode: "CN", CountryName: "China"}, true},
+				{"89.160.20.200", Location{CountryCode: "SE", CountryName: strings.Repeat("Sweden ", 50)}, true},
+				{"89.160.20.100", Location{}, false},
+				{"81.2.70.1", Location{}, false},
+				{"10.0.0.1", Location{}, false},
+				{"2001:db8::1", Location{CountryCode: "DE", CountryName: "DE"}, ipVersion == 6},
+				{"not an address", Location{}, false},
+			} {
+				location, found := reader.Lookup(test.address)
+				if !found {
+					location = Location{}
+				}
+				if found != test.found || (found && location != test.location) {
+					t.Errorf("IPv%d, %d-bit records: Lookup(%s) = %+v, %v, want %+v, %v", ipVersion, recordSize, test.address, location, found, test.location, test.found)
+				}
+			}
+		}
+	}
+}
+
+func TestNewRejectsInvalidFiles(t *testing.T) {
+	valid := buildFixture(t, 4, 24, []fixtureNetwork{{netip.MustParsePrefix("81.2.69.0/24"), map[string]any{"country": map[string]any{"iso_code": "GB"}}}})
+	unsupported := bytes.Replace(valid, []byte("record_size\xa2\x00\x18"), []byte("record_size\xa2\x00\x10"), 1)
+	truncated := valid[bytes.LastIndex(valid, metadataMarker)-4:]
+	for _, test := range []struct {
+		name   string
+		buffer []byte
+		want   string
+	}{
+		{"no metadata", []byte("not a database"), "metadata marker not found"},
+		{"unsupported record size", unsupported, "unsupported record size 16"},
+		{"truncated tree", truncated, "search tree is larger than the file"},
+	} {
+		_, err := New(test.buffer)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: New = %v, want an error containing %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestReadNode(t *testing.T) {
+	for _, test := range []struct {
+		recordSize  uint
+		buffer      []byte
+		left, right uint
+	}{
+		{24, []byte{0x12, 0x34, 0x56, 0xab, 0xcd, 0xef}, 0x123456, 0xabcdef},
+		{28, []byte{0x12, 0x34, 0x56, 0x9a, 0xbc, 0xde, 0xf0}, 0x9123456, 0xabcdef0},
+		{32, []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}, 0x12345678, 0x9abcdef0},
+	} {
+		reader := &Reader{buffer: test.buffer, recordSize: test.recordSize}
+		if left, right := reader.readNode(0, 0), reader.readNode(0, 1); left != test.left || right != test.right {
+			t.Errorf("%d-bit records: readNode = %#x, %#x, want %#x, %#x", test.recordSize, left, right, test.left, test.right)
+		}
+	}
+}
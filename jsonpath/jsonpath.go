@@ -0,0 +1,92 @@
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type step struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+type Path struct {
+	source string
+	steps  []step
+}
+
+func Parse(source string) (Path, error) {
+	path := Path{source: source}
+	rest := strings.TrimSpace(source)
+	rest = strings.TrimPrefix(rest, "$")
+	if rest == "" {
+		return path, fmt.Errorf("path %q is empty", source)
+	}
+	if !strings.HasPrefix(rest, ".") && !strings.HasPrefix(rest, "[") {
+		rest = "." + rest
+	}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return path, fmt.Errorf("path %q has an empty key", source)
+			}
+			path.steps = append(path.steps, step{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return path, fmt.Errorf("path %q has an unclosed [", source)
+			}
+			inside := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if len(inside) >= 2 && (inside[0] == '\'' || inside[0] == '"') && inside[len(inside)-1] == inside[0] {
+				path.steps = append(path.steps, step{key: inside[1 : len(inside)-1]})
+				continue
+			}
+			index, err := strconv.Atoi(inside)
+			if err != nil || index < 0 {
+				return path, fmt.Errorf("path %q has an invalid index [%s]", source, inside)
+			}
+			path.steps = append(path.steps, step{index: index, isIndex: true})
+		default:
+			return path, fmt.Errorf("path %q has an unexpected %q", source, rest[0])
+		}
+	}
+	return path, nil
+}
+
+func (p Path) String() string {
+	return p.source
+}
+
+func (p Path) Get(document any) (any, bool) {
+	current := document
+	for _, s := range p.steps {
+		if s.isIndex {
+			array, isArray := current.([]any)
+			if !isArray || s.index >= len(array) {
+				return nil, false
+			}
+			current = array[s.index]
+			continue
+		}
+		object, isObject := current.(map[string]any)
+		if !isObject {
+			return nil, false
+		}
+		value, exists := object[s.key]
+		if !exists {
+			return nil, false
+		}
+		current = value
+	}
+	return current, current != nil
+}
@@ -0,0 +1,87 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const document = `{
+	"user": {
+		"data": {"plan": "pro", "company.name": "Acme", "seats": 5, "empty": null},
+		"tags": ["a", {"name": "b"}],
+		"memberships": [[1, 2], [3]]
+	}
+}`
+
+func TestGet(t *testing.T) {
+	var value any
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		path   string
+		want   any
+		exists bool
+	}{
+		{"user.data.plan", "pro", true},
+		{"$.user.data.plan", "pro", true},
+		{"  $.user.data.plan ", "pro", true},
+		{"$['user']['data'][\"plan\"]", "pro", true},
+		{"$[\"user\"].data['company.name']", "Acme", true},
+		{"user.data.seats", 5.0, true},
+		{"user.tags[0]", "a", true},
+		{"user.tags[1].name", "b", true},
+		{"user.tags[ 1 ].name", "b", true},
+		{"user.memberships[0][1]", 2.0, true},
+		{"user.data", map[string]any{"plan": "pro", "company.name": "Acme", "seats": 5.0, "empty": nil}, true},
+		{"user.data.empty", nil, false},
+		{"user.data.missing", nil, false},
+		{"user.tags[2]", nil, false},
+		{"user.data[0]", nil, false},
+		{"user.tags.name", nil, false},
+		{"user.data.plan.name", nil, false},
+	} {
+		path, err := Parse(test.path)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.path, err)
+			continue
+		}
+		got, exists := path.Get(value)
+		if exists != test.exists || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Get(%q) = %v, %v, want %v, %v", test.path, got, exists, test.want, test.exists)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want string
+	}{
+		{"", `path "" is empty`},
+		{"$", `path "$" is empty`},
+		{"user..plan", `path "user..plan" has an empty key`},
+		{"user.", `path "user." has an empty key`},
+		{"user[0", `path "user[0" has an unclosed [`},
+		{"user[-1]", `path "user[-1]" has an invalid index [-1]`},
+		{"user[x]", `path "user[x]" has an invalid index [x]`},
+		{"user['x\"]", `path "user['x\"]" has an invalid index ['x"]`},
+		{"user[0]x", `path "user[0]x" has an unexpected 'x'`},
+	} {
+		_, err := Parse(test.path)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Parse(%q) = %v, want %s", test.path, err, test.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	path, err := Parse("$.user.data.plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path.String() != "$.user.data.plan" {
+		t.Errorf("String() = %q", path.String())
+	}
+}
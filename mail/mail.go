@@ -0,0 +1,195 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const base64LineLength = 76
+
+type Options struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+type Message struct {
+	Subject string
+	Text    string
+	Html    string
+	Inline  []Inline
+}
+
+type Inline struct {
+	ContentId   string
+	ContentType string
+	Data        []byte
+}
+
+func (o Options) Validate() error {
+	var errs []error
+	if o.Host == "" || o.Port < 1 || o.Port > 65535 {
+		errs = append(errs, errors.New("smtp: host and a port from 1 to 65535 are required"))
+	}
+	if o.From == "" || len(o.To) == 0 {
+		errs = append(errs, errors.New("smtp: from and at least one to address are required"))
+	}
+	return errors.Join(errs...)
+}
+
+func Send(ctx context.Context, options Options, message Message) error {
+	body, err := Build(options, message, time.Now())
+	if err != nil {
+		return err
+	}
+	address := net.JoinHostPort(options.Host, strconv.Itoa(options.Port))
+	if err := send(ctx, address, options, body); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("smtp %s: %w", address, err)
+	}
+	return nil
+}
+
+func send(ctx context.Context, address string, options Options, body []byte) error {
+	var dialer net.Dialer
+	connection, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+	stop := context.AfterFunc(ctx, func() { connection.SetDeadline(time.Now()) })
+	defer stop()
+	client, err := smtp.NewClient(connection, options.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if hasTls, _ := client.Extension("STARTTLS"); hasTls {
+		if err := client.StartTLS(&tls.Config{ServerName: options.Host}); err != nil {
+			return err
+		}
+	}
+	if options.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", options.Username, options.Password, options.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(options.From); err != nil {
+		return err
+	}
+	for _, to := range options.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func Build(options Options, message Message, now time.Time) ([]byte, error) {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n",
+		options.From, strings.Join(options.To, ", "), mime.QEncoding.Encode("utf-8", message.Subject), now.Format(time.RFC1123Z))
+	if message.Html == "" {
+		buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buffer, message.Text); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+	var alternativeBody bytes.Buffer
+	alternative := multipart.NewWriter(&alternativeBody)
+	textPart, err := alternative.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}, "Content-Transfer-Encoding": {"quoted-printable"}})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintable(textPart, message.Text); err != nil {
+		return nil, err
+	}
+	htmlBody, htmlHeader, err := getHtmlPart(message)
+	if err != nil {
+		return nil, err
+	}
+	htmlPart, err := alternative.CreatePart(htmlHeader)
+	if err != nil {
+		return nil, err
+	}
+	htmlPart.Write(htmlBody)
+	if err := alternative.Close(); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&buffer, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", alternative.Boundary())
+	buffer.Write(alternativeBody.Bytes())
+	return buffer.Bytes(), nil
+}
+
+func getHtmlPart(message Message) ([]byte, textproto.MIMEHeader, error) {
+	var body bytes.Buffer
+	if len(message.Inline) == 0 {
+		err := writeQuotedPrintable(&body, message.Html)
+		return body.Bytes(), textproto.MIMEHeader{"Content-Type": {"text/html; charset=utf-8"}, "Content-Transfer-Encoding": {"quoted-printable"}}, err
+	}
+	related := multipart.NewWriter(&body)
+	htmlPart, err := related.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=utf-8"}, "Content-Transfer-Encoding": {"quoted-printable"}})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeQuotedPrintable(htmlPart, message.Html); err != nil {
+		return nil, nil, err
+	}
+	for _, inline := range message.Inline {
+		inlinePart, err := related.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {inline.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Id":                {"<" + inline.ContentId + ">"},
+			"Content-Disposition":       {"inline"},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(inline.Data)
+		for start := 0; start < len(encoded); start += base64LineLength {
+			fmt.Fprintf(inlinePart, "%s\r\n", encoded[start:min(start+base64LineLength, len(encoded))])
+		}
+	}
+	if err := related.Close(); err != nil {
+		return nil, nil, err
+	}
+	return body.Bytes(), textproto.MIMEHeader{"Content-Type": {fmt.Sprintf("multipart/related; boundary=%q", related.Boundary())}}, nil
+}
+
+func writeQuotedPrintable(writer io.Writer, text string) error {
+	encoder := quotedprintable.NewWriter(writer)
+	if _, err := encoder.Write([]byte(text)); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
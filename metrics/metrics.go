@@ -0,0 +1,363 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SourceLogins        = "logins"
+	SourceRegistrations = "registrations"
+
+	AggregateUsers  = "users"
+	AggregateEvents = "events"
+
+	BucketEventCount = "eventCount"
+	BucketYear       = "year"
+	BucketMonth      = "month"
+	BucketWeek       = "week"
+	BucketDay        = "day"
+
+	DimensionVerified         = "verified"
+	DimensionCountry          = "country"
+	DimensionEmailDomain      = "emailDomain"
+	DimensionDomainType       = "domainType"
+	DimensionApplication      = "application"
+	DimensionIdentityProvider = "identityProvider"
+	AttributePrefix           = "attribute:"
+
+	GroupBySplit = "split"
+	GroupByNone  = "none"
+
+	NoValue  = "(none)"
+	AllUsers = "All users"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+var timeBuckets = []string{BucketYear, BucketMonth, BucketWeek, BucketDay}
+var userDimensions = []string{DimensionVerified, DimensionCountry, DimensionEmailDomain, DimensionDomainType}
+var loginDimensions = []string{DimensionApplication, DimensionIdentityProvider}
+
+type Definition struct {
+	Name       string `json:"name"`
+	Title      string `json:"title"`
+	Source     string `json:"source"`
+	WindowDays int    `json:"windowDays"`
+	BucketBy   string `json:"bucketBy"`
+	Thresholds []int  `json:"thresholds"`
+	GroupBy    string `json:"groupBy"`
+	Aggregate  string `json:"aggregate"`
+	XAxisTitle string `json:"xAxisTitle"`
+}
+
+type User struct {
+	Id               string
+	IsVerified       bool
+	RegisteredDate   time.Time
+	Country          string
+	EmailDomain      string
+	IsConsumerDomain bool
+	Attributes       map[string]string
+	Series           string
+	Logins           []Login
+}
+
+type Login struct {
+	Date             time.Time
+	Application      string
+	IdentityProvider string
+}
+
+type Result struct {
+	Labels  []string
+	Series  []Series
+	Unit    string
+	Stacked bool
+}
+
+type Series struct {
+	Name string
+	Data []float64
+}
+
+func (d Definition) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("metric %q: %s", d.Name, fmt.Sprintf(format, args...)))
+	}
+	if !namePattern.MatchString(d.Name) {
+		fail("name must start with a letter and contain only letters, digits, '-' and '_'")
+	}
+	if d.Title == "" {
+		fail("title is required")
+	}
+	if d.Source != SourceLogins && d.Source != SourceRegistrations {
+		fail("source %q must be %q or %q", d.Source, SourceLogins, SourceRegistrations)
+	}
+	if d.WindowDays < 0 {
+		fail("windowDays must be 0 (all time) or more")
+	}
+	if d.Aggregate != "" && d.Aggregate != AggregateUsers && d.Aggregate != AggregateEvents {
+		fail("aggregate %q must be %q or %q", d.Aggregate, AggregateUsers, AggregateEvents)
+	}
+	switch {
+	case d.BucketBy == BucketEventCount:
+		if len(d.Thresholds) == 0 {
+			fail("bucketBy %q needs thresholds", BucketEventCount)
+		}
+		for index, threshold := range d.Thresholds {
+			if threshold < 0 || (index > 0 && threshold <= d.Thresholds[index-1]) {
+				fail("thresholds must be ascending and not negative, got %v", d.Thresholds)
+				break
+			}
+		}
+		if d.Aggregate == AggregateEvents {
+			fail("bucketBy %q counts users, so aggregate must be %q", BucketEventCount, AggregateUsers)
+		}
+	case slices.Contains(timeBuckets, d.BucketBy) || d.isDimension(d.BucketBy):
+		if len(d.Thresholds) > 0 {
+			fail("thresholds are only used with bucketBy %q", BucketEventCount)
+		}
+	case slices.Contains(loginDimensions, d.BucketBy):
+		fail("bucketBy %q needs source %q", d.BucketBy, SourceLogins)
+	default:
+		fail("bucketBy %q must be %q, one of %s, one of %s, or %s<name>", d.BucketBy, BucketEventCount, strings.Join(timeBuckets, ", "), strings.Join(slices.Concat(userDimensions, loginDimensions), ", "), AttributePrefix)
+	}
+	if d.GroupBy != "" && d.GroupBy != GroupBySplit && d.GroupBy != GroupByNone {
+		if !d.isDimension(d.GroupBy) {
+			fail("groupBy %q must be %q, %q, a dimension, or %s<name>", d.GroupBy, GroupBySplit, GroupByNone, AttributePrefix)
+		} else if d.BucketBy == BucketEventCount && slices.Contains(loginDimensions, d.GroupBy) {
+			fail("groupBy %q is per login, so it can't be used with bucketBy %q", d.GroupBy, BucketEventCount)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d Definition) isDimension(value string) bool {
+	if strings.HasPrefix(value, AttributePrefix) {
+		return len(value) > len(AttributePrefix)
+	}
+	return slices.Contains(userDimensions, value) || (d.Source == SourceLogins && slices.Contains(loginDimensions, value))
+}
+
+func Evaluate(definition Definition, users []User, now time.Time) Result {
+	counts := make(map[string]map[string]float64)
+	add := func(label string, group string) {
+		if _, exists := counts[label]; !exists {
+			counts[label] = make(map[string]float64)
+		}
+		counts[label][group]++
+	}
+	thresholdLabels := getThresholdLabels(definition.Thresholds)
+	var first, last time.Time
+	for _, user := range users {
+		events := getEvents(definition, user, now)
+		if definition.BucketBy == BucketEventCount {
+			if index := getThresholdIndex(definition.Thresholds, len(events)); index != -1 {
+				add(thresholdLabels[index], getGroup(definition, user, Login{}))
+			}
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, event := range events {
+			if first.IsZero() || event.Date.Before(first) {
+				first = event.Date
+			}
+			if event.Date.After(last) {
+				last = event.Date
+			}
+			label, group := getBucket(definition, user, event), getGroup(definition, user, event)
+			if definition.Aggregate == AggregateEvents {
+				add(label, group)
+			} else if !seen[label+"\x00"+group] {
+				seen[label+"\x00"+group] = true
+				add(label, group)
+			}
+		}
+	}
+	result := Result{Labels: []string{}, Series: []Series{}, Unit: AggregateUsers, Stacked: true}
+	if definition.Aggregate == AggregateEvents {
+		result.Unit = definition.Source
+	}
+	if definition.BucketBy == BucketEventCount {
+		result.Labels = thresholdLabels
+	} else {
+		labels := make(map[string]bool)
+		for label := range counts {
+			labels[label] = true
+		}
+		if slices.Contains(timeBuckets, definition.BucketBy) {
+			start, end := first, now
+			if definition.WindowDays > 0 {
+				start = now.AddDate(0, 0, -definition.WindowDays)
+			}
+			if last.After(end) {
+				end = last
+			}
+			if !start.IsZero() {
+				for _, label := range getTimeLabels(definition.BucketBy, start, end) {
+					labels[label] = true
+				}
+			}
+		}
+		for label := range labels {
+			result.Labels = append(result.Labels, label)
+		}
+		sort.Strings(result.Labels)
+	}
+	groups := make(map[string]bool)
+	for _, groupCounts := range counts {
+		for group := range groupCounts {
+			groups[group] = true
+		}
+	}
+	groupNames := make([]string, 0, len(groups))
+	for group := range groups {
+		groupNames = append(groupNames, group)
+	}
+	sort.Strings(groupNames)
+	for _, group := range groupNames {
+		series := Series{Name: group, Data: make([]float64, len(result.Labels))}
+		for index, label := range result.Labels {
+			series.Data[index] = counts[label][group]
+		}
+		result.Series = append(result.Series, series)
+	}
+	return result
+}
+
+func getEvents(definition Definition, user User, now time.Time) []Login {
+	events := user.Logins
+	if definition.Source == SourceRegistrations {
+		events = []Login{{Date: user.RegisteredDate}}
+	}
+	if definition.WindowDays == 0 {
+		return events
+	}
+	windowStart := now.AddDate(0, 0, -definition.WindowDays)
+	var inWindow []Login
+	for _, event := range events {
+		if !event.Date.Before(windowStart) && !event.Date.After(now) {
+			inWindow = append(inWindow, event)
+		}
+	}
+	return inWindow
+}
+
+func getBucket(definition Definition, user User, event Login) string {
+	switch definition.BucketBy {
+	case BucketYear:
+		return event.Date.Format("2006")
+	case BucketMonth:
+		return event.Date.Format("2006-01")
+	case BucketWeek:
+		year, week := event.Date.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case BucketDay:
+		return event.Date.Format("2006-01-02")
+	}
+	return getDimension(definition.BucketBy, user, event)
+}
+
+func getTimeLabels(bucketBy string, start time.Time, end time.Time) []string {
+	labels := []string{}
+	for current := getBucketStart(bucketBy, start); !current.After(end); current = getNextBucket(bucketBy, current) {
+		labels = append(labels, getBucket(Definition{BucketBy: bucketBy}, User{}, Login{Date: current}))
+	}
+	return labels
+}
+
+func getBucketStart(bucketBy string, date time.Time) time.Time {
+	year, month, day := date.Date()
+	switch bucketBy {
+	case BucketYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, date.Location())
+	case BucketMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, date.Location())
+	case BucketWeek:
+		return time.Date(year, month, day-(int(date.Weekday())+6)%7, 0, 0, 0, 0, date.Location())
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, date.Location())
+}
+
+func getNextBucket(bucketBy string, date time.Time) time.Time {
+	switch bucketBy {
+	case BucketYear:
+		return date.AddDate(1, 0, 0)
+	case BucketMonth:
+		return date.AddDate(0, 1, 0)
+	case BucketWeek:
+		return date.AddDate(0, 0, 7)
+	}
+	return date.AddDate(0, 0, 1)
+}
+
+func getGroup(definition Definition, user User, event Login) string {
+	switch definition.GroupBy {
+	case "", GroupBySplit:
+		return user.Series
+	case GroupByNone:
+		return AllUsers
+	}
+	return getDimension(definition.GroupBy, user, event)
+}
+
+func getDimension(dimension string, user User, event Login) string {
+	value := ""
+	switch dimension {
+	case DimensionVerified:
+		value = "Unverified"
+		if user.IsVerified {
+			value = "Verified"
+		}
+	case DimensionCountry:
+		value = user.Country
+	case DimensionEmailDomain:
+		value = user.EmailDomain
+	case DimensionDomainType:
+		value = "Corporate"
+		if user.IsConsumerDomain {
+			value = "Consumer"
+		}
+	case DimensionApplication:
+		value = event.Application
+	case DimensionIdentityProvider:
+		value = event.IdentityProvider
+	default:
+		value = user.Attributes[strings.TrimPrefix(dimension, AttributePrefix)]
+	}
+	if value == "" {
+		return NoValue
+	}
+	return value
+}
+
+func getThresholdIndex(thresholds []int, count int) int {
+	index := -1
+	for thresholdIndex, threshold := range thresholds {
+		if count >= threshold {
+			index = thresholdIndex
+		}
+	}
+	return index
+}
+
+func getThresholdLabels(thresholds []int) []string {
+	labels := make([]string, len(thresholds))
+	for index, threshold := range thresholds {
+		switch {
+		case index == len(thresholds)-1:
+			labels[index] = fmt.Sprintf("%d+", threshold)
+		case thresholds[index+1] == threshold+1:
+			labels[index] = fmt.Sprintf("%d", threshold)
+		default:
+			labels[index] = fmt.Sprintf("%d-%d", threshold, thresholds[index+1]-1)
+		}
+	}
+	return labels
+}
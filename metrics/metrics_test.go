@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEvaluateFillsTimeBuckets(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	for _, test := range []struct {
+		bucketBy   string
+		windowDays int
+		logins     []time.Time
+		now        time.Time
+		labels     []string
+		data       []float64
+	}{
+		{BucketDay, 7, []time.Time{date(2025, 1, 5), date(2025, 1, 8)}, date(2025, 1, 10),
+			[]string{"2025-01-03", "2025-01-04", "2025-01-05", "2025-01-06", "2025-01-07", "2025-01-08", "2025-01-09", "2025-01-10"}, []float64{0, 0, 1, 0, 0, 1, 0, 0}},
+		{BucketWeek, 0, []time.Time{date(2024, 12, 30), date(2025, 1, 20)}, date(2025, 1, 22),
+			[]string{"2025-W01", "2025-W02", "2025-W03", "2025-W04"}, []float64{1, 0, 0, 1}},
+		{BucketMonth, 0, []time.Time{date(2024, 11, 15), date(2025, 2, 1)}, date(2025, 2, 10),
+			[]string{"2024-11", "2024-12", "2025-01", "2025-02"}, []float64{1, 0, 0, 1}},
+		{BucketYear, 0, []time.Time{date(2022, 6, 1), date(2024, 6, 1)}, date(2025, 3, 1),
+			[]string{"2022", "2023", "2024", "2025"}, []float64{1, 0, 1, 0}},
+		{BucketDay, 0, nil, date(2025, 1, 10), []string{}, nil},
+	} {
+		user := User{Id: "user"}
+		for _, login := range test.logins {
+			user.Logins = append(user.Logins, Login{Date: login})
+		}
+		definition := Definition{Name: "logins", Source: SourceLogins, WindowDays: test.windowDays, BucketBy: test.bucketBy, GroupBy: GroupByNone, Aggregate: AggregateEvents}
+		result := Evaluate(definition, []User{user}, test.now)
+		if !slices.Equal(result.Labels, test.labels) {
+			t.Errorf("%s over %d days: labels = %v, want %v", test.bucketBy, test.windowDays, result.Labels, test.labels)
+			continue
+		}
+		if test.data == nil {
+			if len(result.Series) != 0 {
+				t.Errorf("%s without events: series = %+v, want none", test.bucketBy, result.Series)
+			}
+			continue
+		}
+		if len(result.Series) != 1 || !slices.Equal(result.Series[0].Data, test.data) {
+			t.Errorf("%s over %d days: series = %+v, want %v", test.bucketBy, test.windowDays, result.Series, test.data)
+		}
+	}
+}
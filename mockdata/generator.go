@@ -0,0 +1,166 @@
+package mockdata
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"app/fusionauth"
+)
+
+type Options struct {
+	NumberOfUsers        int
+	Seed                 uint64
+	EmailDomain          string
+	RegistrationStart    time.Time
+	RegistrationEnd      time.Time
+	LoginEnd             time.Time
+	AverageLoginInterval time.Duration
+	UnverifiedShare      float64
+	Applications         []string
+}
+
+type User struct {
+	Id             string            `json:"id"`
+	Email          string            `json:"email"`
+	IsVerified     bool              `json:"isVerified"`
+	RegisteredDate int64             `json:"registeredDate"`
+	LoginDates     []int64           `json:"loginDates"`
+	Logins         []Login           `json:"logins"`
+	Segment        string            `json:"segment,omitempty"`
+	Attributes     map[string]string `json:"attributes,omitempty"`
+}
+
+type Login struct {
+	Instant          int64  `json:"instant"`
+	IpAddress        string `json:"ipAddress,omitempty"`
+	ApplicationName  string `json:"applicationName,omitempty"`
+	IdentityProvider string `json:"identityProvider,omitempty"`
+}
+
+const signupSourceAttribute = "signupSource"
+
+var identityProviderShares = []struct {
+	Name  string
+	Share float64
+}{{"Password", 0.75}, {"Google", 0.15}, {"SAML", 0.1}}
+
+func DefaultOptions() Options {
+	return Options{
+		NumberOfUsers:        1000,
+		Seed:                 1,
+		EmailDomain:          "example.com",
+		RegistrationStart:    time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC),
+		RegistrationEnd:      time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC),
+		LoginEnd:             time.Date(2025, 12, 30, 0, 0, 0, 0, time.UTC),
+		AverageLoginInterval: 15 * 24 * time.Hour,
+		UnverifiedShare:      0.05,
+		Applications:         []string{"Example app"},
+	}
+}
+
+func Generate(options Options) []User {
+	random := rand.New(rand.NewPCG(options.Seed, options.Seed))
+	users := make([]User, 0, options.NumberOfUsers)
+	registrationDays := int(options.RegistrationEnd.Sub(options.RegistrationStart).Hours() / 24)
+	for i := 1; i <= options.NumberOfUsers; i++ {
+		registeredDate := options.RegistrationStart.AddDate(0, 0, random.IntN(max(registrationDays, 1)))
+		user := User{
+			Id:             newId(random),
+			Email:          fmt.Sprintf("%d@%s", i, options.EmailDomain),
+			IsVerified:     random.Float64() >= options.UnverifiedShare,
+			RegisteredDate: registeredDate.UnixMilli(),
+			LoginDates:     []int64{},
+		}
+		if user.IsVerified {
+			user.LoginDates = generateLoginDates(random, registeredDate, options.LoginEnd, options.AverageLoginInterval)
+		}
+		user.Logins = generateLogins(random, user.LoginDates, options.Applications)
+		users = append(users, user)
+	}
+	return users
+}
+
+func generateLoginDates(random *rand.Rand, registeredDate time.Time, loginEnd time.Time, averageLoginInterval time.Duration) []int64 {
+	loginDates := []int64{}
+	window := loginEnd.Sub(registeredDate)
+	if window <= 0 || averageLoginInterval <= 0 {
+		return loginDates
+	}
+	maxLoginsLimit := int(window / averageLoginInterval)
+	numberOfLogins := random.IntN(maxLoginsLimit + 1)
+	for i := 0; i < numberOfLogins; i++ {
+		offset := time.Duration(random.Int64N(int64(window/time.Millisecond))) * time.Millisecond
+		loginDates = append(loginDates, registeredDate.Add(offset).UnixMilli())
+	}
+	sort.Slice(loginDates, func(i, j int) bool { return loginDates[i] < loginDates[j] })
+	return loginDates
+}
+
+func generateLogins(random *rand.Rand, loginDates []int64, applications []string) []Login {
+	logins := []Login{}
+	ipAddresses := make([]string, 1+random.IntN(3))
+	for index := range ipAddresses {
+		ipAddresses[index] = fmt.Sprintf("%d.%d.%d.%d", 1+random.IntN(223), random.IntN(256), random.IntN(256), 1+random.IntN(254))
+	}
+	identityProvider := identityProviderShares[0].Name
+	target := random.Float64()
+	for _, provider := range identityProviderShares {
+		if target < provider.Share {
+			identityProvider = provider.Name
+			break
+		}
+		target -= provider.Share
+	}
+	for _, loginDate := range loginDates {
+		login := Login{Instant: loginDate, IpAddress: ipAddresses[random.IntN(len(ipAddresses))], IdentityProvider: identityProvider}
+		if len(applications) > 0 {
+			login.ApplicationName = applications[random.IntN(len(applications))]
+		}
+		logins = append(logins, login)
+	}
+	return logins
+}
+
+func ToFusionAuthUsers(users []User, applicationId string) []fusionauth.User {
+	faUsers := make([]fusionauth.User, 0, len(users))
+	for _, user := range users {
+		faUsers = append(faUsers, fusionauth.User{
+			Id:            user.Id,
+			Email:         user.Email,
+			Verified:      user.IsVerified,
+			InsertInstant: user.RegisteredDate,
+			Registrations: []fusionauth.Registration{{ApplicationId: applicationId, InsertInstant: user.RegisteredDate, Verified: user.IsVerified, Data: getSignupData(user.Attributes)}},
+			Data:          getUserData(user.Attributes),
+		})
+	}
+	return faUsers
+}
+
+func getUserData(attributes map[string]string) map[string]any {
+	data := make(map[string]any)
+	for name, value := range attributes {
+		if name != signupSourceAttribute {
+			data[name] = value
+		}
+	}
+	return data
+}
+
+func getSignupData(attributes map[string]string) map[string]any {
+	if source, exists := attributes[signupSourceAttribute]; exists {
+		return map[string]any{signupSourceAttribute: source}
+	}
+	return nil
+}
+
+func newId(random *rand.Rand) string {
+	bytes := make([]byte, 16)
+	for index := range bytes {
+		bytes[index] = byte(random.UintN(256))
+	}
+	bytes[6] = (bytes[6] & 0x0f) | 0x40
+	bytes[8] = (bytes[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:])
+}
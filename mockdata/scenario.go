@@ -0,0 +1,246 @@
+package mockdata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sort"
+	"time"
+)
+
+type Date struct {
+	time.Time
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return fmt.Errorf("date %q must be in the format YYYY-MM-DD", value)
+	}
+	d.Time = parsed
+	return nil
+}
+
+type Scenario struct {
+	Seed              uint64     `json:"seed"`
+	NumberOfUsers     int        `json:"numberOfUsers"`
+	EmailDomain       string     `json:"emailDomain"`
+	RegistrationStart Date       `json:"registrationStart"`
+	RegistrationEnd   Date       `json:"registrationEnd"`
+	LoginEnd          Date       `json:"loginEnd"`
+	Applications      []string   `json:"applications"`
+	Segments          []Segment  `json:"segments"`
+	Campaigns         []Campaign `json:"campaigns"`
+}
+
+type Segment struct {
+	Name                string              `json:"name"`
+	Share               float64             `json:"share"`
+	UnverifiedShare     float64             `json:"unverifiedShare"`
+	LoginIntervalDays   float64             `json:"loginIntervalDays"`
+	FirstLoginDelayDays float64             `json:"firstLoginDelayDays"`
+	MaxLogins           int                 `json:"maxLogins"`
+	ActiveMonths        []int               `json:"activeMonths"`
+	ChurnAfterMonths    int                 `json:"churnAfterMonths"`
+	EmailDomains        []string            `json:"emailDomains"`
+	Attributes          map[string][]string `json:"attributes"`
+}
+
+type Campaign struct {
+	Name  string  `json:"name"`
+	Start Date    `json:"start"`
+	Days  int     `json:"days"`
+	Share float64 `json:"share"`
+}
+
+func LoadScenario(path string) (Scenario, error) {
+	var scenario Scenario
+	fileContent, err := os.ReadFile(path)
+	if err != nil {
+		return scenario, err
+	}
+	if err := json.Unmarshal(fileContent, &scenario); err != nil {
+		return scenario, fmt.Errorf("%s: %w", path, err)
+	}
+	defaults := DefaultOptions()
+	if scenario.EmailDomain == "" {
+		scenario.EmailDomain = defaults.EmailDomain
+	}
+	if scenario.RegistrationStart.IsZero() {
+		scenario.RegistrationStart.Time = defaults.RegistrationStart
+	}
+	if scenario.RegistrationEnd.IsZero() {
+		scenario.RegistrationEnd.Time = defaults.RegistrationEnd
+	}
+	if scenario.LoginEnd.IsZero() {
+		scenario.LoginEnd.Time = defaults.LoginEnd
+	}
+	if len(scenario.Applications) == 0 {
+		scenario.Applications = defaults.Applications
+	}
+	if err := scenario.Validate(); err != nil {
+		return scenario, fmt.Errorf("%s: %w", path, err)
+	}
+	return scenario, nil
+}
+
+func (s Scenario) Validate() error {
+	var errs []error
+	if s.NumberOfUsers <= 0 {
+		errs = append(errs, errors.New("numberOfUsers must be greater than 0"))
+	}
+	if !s.RegistrationEnd.After(s.RegistrationStart.Time) {
+		errs = append(errs, errors.New("registrationEnd must be after registrationStart"))
+	}
+	if s.LoginEnd.Before(s.RegistrationEnd.Time) {
+		errs = append(errs, errors.New("loginEnd must not be before registrationEnd"))
+	}
+	if len(s.Segments) == 0 {
+		errs = append(errs, errors.New("at least one segment is required"))
+	}
+	totalShare := 0.0
+	for index, segment := range s.Segments {
+		prefix := fmt.Sprintf("segments[%d] (%s)", index, segment.Name)
+		if segment.Share < 0 {
+			errs = append(errs, fmt.Errorf("%s: share must not be negative", prefix))
+		}
+		if segment.UnverifiedShare < 0 || segment.UnverifiedShare > 1 {
+			errs = append(errs, fmt.Errorf("%s: unverifiedShare must be between 0 and 1", prefix))
+		}
+		if segment.LoginIntervalDays < 0 || segment.FirstLoginDelayDays < 0 || segment.MaxLogins < 0 || segment.ChurnAfterMonths < 0 {
+			errs = append(errs, fmt.Errorf("%s: loginIntervalDays, firstLoginDelayDays, maxLogins and churnAfterMonths must not be negative", prefix))
+		}
+		for _, month := range segment.ActiveMonths {
+			if month < 1 || month > 12 {
+				errs = append(errs, fmt.Errorf("%s: activeMonths must be between 1 and 12, got %d", prefix, month))
+			}
+		}
+		totalShare += segment.Share
+	}
+	if len(s.Segments) > 0 && totalShare <= 0 {
+		errs = append(errs, errors.New("segment shares must add up to more than 0"))
+	}
+	campaignShare := 0.0
+	for index, campaign := range s.Campaigns {
+		prefix := fmt.Sprintf("campaigns[%d] (%s)", index, campaign.Name)
+		if campaign.Days <= 0 {
+			errs = append(errs, fmt.Errorf("%s: days must be greater than 0", prefix))
+		}
+		if campaign.Share < 0 {
+			errs = append(errs, fmt.Errorf("%s: share must not be negative", prefix))
+		}
+		if campaign.Start.Before(s.RegistrationStart.Time) || campaign.Start.After(s.RegistrationEnd.Time) {
+			errs = append(errs, fmt.Errorf("%s: start must be between registrationStart and registrationEnd", prefix))
+		}
+		campaignShare += campaign.Share
+	}
+	if campaignShare > 1 {
+		errs = append(errs, errors.New("campaign shares must not add up to more than 1"))
+	}
+	return errors.Join(errs...)
+}
+
+func GenerateScenario(scenario Scenario) []User {
+	random := rand.New(rand.NewPCG(scenario.Seed, scenario.Seed))
+	users := make([]User, 0, scenario.NumberOfUsers)
+	for i := 1; i <= scenario.NumberOfUsers; i++ {
+		segment := pickSegment(random, scenario.Segments)
+		registeredDate := pickRegistrationDate(random, scenario)
+		emailDomain := scenario.EmailDomain
+		if len(segment.EmailDomains) > 0 {
+			emailDomain = segment.EmailDomains[random.IntN(len(segment.EmailDomains))]
+		}
+		user := User{
+			Id:             newId(random),
+			Email:          fmt.Sprintf("%d@%s", i, emailDomain),
+			IsVerified:     random.Float64() >= segment.UnverifiedShare,
+			RegisteredDate: registeredDate.UnixMilli(),
+			LoginDates:     []int64{},
+			Segment:        segment.Name,
+		}
+		if len(segment.Attributes) > 0 {
+			user.Attributes = make(map[string]string)
+			names := slices.Sorted(maps.Keys(segment.Attributes))
+			for _, name := range names {
+				if values := segment.Attributes[name]; len(values) > 0 {
+					user.Attributes[name] = values[random.IntN(len(values))]
+				}
+			}
+		}
+		if user.IsVerified {
+			user.LoginDates = generateSegmentLoginDates(random, segment, registeredDate, scenario.LoginEnd.Time)
+		}
+		user.Logins = generateLogins(random, user.LoginDates, scenario.Applications)
+		users = append(users, user)
+	}
+	return users
+}
+
+func pickSegment(random *rand.Rand, segments []Segment) Segment {
+	totalShare := 0.0
+	for _, segment := range segments {
+		totalShare += segment.Share
+	}
+	target := random.Float64() * totalShare
+	for _, segment := range segments {
+		if target < segment.Share {
+			return segment
+		}
+		target -= segment.Share
+	}
+	return segments[len(segments)-1]
+}
+
+func pickRegistrationDate(random *rand.Rand, scenario Scenario) time.Time {
+	target := random.Float64()
+	for _, campaign := range scenario.Campaigns {
+		if target < campaign.Share {
+			return campaign.Start.AddDate(0, 0, random.IntN(campaign.Days))
+		}
+		target -= campaign.Share
+	}
+	registrationDays := int(scenario.RegistrationEnd.Sub(scenario.RegistrationStart.Time).Hours() / 24)
+	return scenario.RegistrationStart.AddDate(0, 0, random.IntN(max(registrationDays, 1)))
+}
+
+func generateSegmentLoginDates(random *rand.Rand, segment Segment, registeredDate time.Time, loginEnd time.Time) []int64 {
+	start := registeredDate.Add(time.Duration(segment.FirstLoginDelayDays * float64(24*time.Hour)))
+	end := loginEnd
+	if segment.ChurnAfterMonths > 0 {
+		churnDate := registeredDate.AddDate(0, segment.ChurnAfterMonths, 0)
+		if churnDate.Before(end) {
+			end = churnDate
+		}
+	}
+	candidates := []time.Time{}
+	if segment.LoginIntervalDays == 0 {
+		for _, loginDate := range generateLoginDates(random, start, end, DefaultOptions().AverageLoginInterval) {
+			candidates = append(candidates, time.UnixMilli(loginDate).UTC())
+		}
+	} else {
+		meanInterval := segment.LoginIntervalDays * float64(24*time.Hour)
+		for loginDate := start.Add(time.Duration(random.Float64() * meanInterval)); loginDate.Before(end); loginDate = loginDate.Add(time.Duration(random.ExpFloat64() * meanInterval)) {
+			candidates = append(candidates, loginDate)
+		}
+	}
+	loginDates := []int64{}
+	for _, loginDate := range candidates {
+		if len(segment.ActiveMonths) > 0 && !slices.Contains(segment.ActiveMonths, int(loginDate.Month())) {
+			continue
+		}
+		if segment.MaxLogins > 0 && len(loginDates) >= segment.MaxLogins {
+			break
+		}
+		loginDates = append(loginDates, loginDate.UnixMilli())
+	}
+	sort.Slice(loginDates, func(i, j int) bool { return loginDates[i] < loginDates[j] })
+	return loginDates
+}
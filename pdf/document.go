@@ -0,0 +1,190 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const PageWidth = 595.28
+const PageHeight = 841.89
+
+const fontRegular = "F1"
+const fontBold = "F2"
+const firstImageObject = 6
+
+type Document struct {
+	Title     string
+	CreatedAt time.Time
+	pages     []*Page
+	images    []imageObject
+}
+
+type Page struct {
+	document *Document
+	content  bytes.Buffer
+	images   []int
+}
+
+type imageObject struct {
+	width  int
+	height int
+	data   []byte
+}
+
+func New(title string, createdAt time.Time) *Document {
+	return &Document{Title: title, CreatedAt: createdAt}
+}
+
+func (d *Document) AddPage() *Page {
+	page := &Page{document: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+func (p *Page) Text(x float64, y float64, text string, size float64, bold bool, fill color.Color) {
+	font := fontRegular
+	if bold {
+		font = fontBold
+	}
+	fmt.Fprintf(&p.content, "BT %s rg /%s %s Tf %s %s Td %s Tj ET\n", getColor(fill), font, number(size), number(x), number(PageHeight-y), getString(encodeText(text)))
+}
+
+func (p *Page) Rect(x float64, y float64, width float64, height float64, fill color.Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n", getColor(fill), number(x), number(PageHeight-y-height), number(width), number(height))
+}
+
+func (p *Page) Line(x1 float64, y1 float64, x2 float64, y2 float64, width float64, stroke color.Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n", getColor(stroke), number(width), number(x1), number(PageHeight-y1), number(x2), number(PageHeight-y2))
+}
+
+func (p *Page) Image(source image.Image, x float64, y float64, width float64, height float64) error {
+	bounds := source.Bounds()
+	pixels := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for row := bounds.Min.Y; row < bounds.Max.Y; row++ {
+		for column := bounds.Min.X; column < bounds.Max.X; column++ {
+			pixel := color.RGBAModel.Convert(source.At(column, row)).(color.RGBA)
+			pixels = append(pixels, pixel.R, pixel.G, pixel.B)
+		}
+	}
+	data, err := compress(pixels)
+	if err != nil {
+		return err
+	}
+	index := len(p.document.images)
+	p.document.images = append(p.document.images, imageObject{width: bounds.Dx(), height: bounds.Dy(), data: data})
+	p.images = append(p.images, index)
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", number(width), number(height), number(x), number(PageHeight-y-height), index)
+	return nil
+}
+
+func (d *Document) Write(writer io.Writer) error {
+	var buffer bytes.Buffer
+	offsets := []int{0}
+	startObject := func(id int) {
+		for len(offsets) <= id {
+			offsets = append(offsets, 0)
+		}
+		offsets[id] = buffer.Len()
+		fmt.Fprintf(&buffer, "%d 0 obj\n", id)
+	}
+	firstPageObject := firstImageObject + len(d.images)
+	buffer.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	startObject(1)
+	buffer.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	startObject(2)
+	kids := make([]string, len(d.pages))
+	for index := range d.pages {
+		kids[index] = fmt.Sprintf("%d 0 R", firstPageObject+index*2)
+	}
+	fmt.Fprintf(&buffer, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(d.pages))
+	startObject(3)
+	buffer.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>\nendobj\n")
+	startObject(4)
+	buffer.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+	startObject(5)
+	fmt.Fprintf(&buffer, "<< /Title %s /CreationDate %s >>\nendobj\n", getString(encodeText(d.Title)), getString([]byte(d.CreatedAt.UTC().Format("D:20060102150405Z"))))
+	for index, object := range d.images {
+		startObject(firstImageObject + index)
+		fmt.Fprintf(&buffer, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n",
+			object.width, object.height, len(object.data))
+		buffer.Write(object.data)
+		buffer.WriteString("\nendstream\nendobj\n")
+	}
+	for index, page := range d.pages {
+		pageObject := firstPageObject + index*2
+		images := make([]string, len(page.images))
+		for imageIndex, object := range page.images {
+			images[imageIndex] = fmt.Sprintf("/Im%d %d 0 R", object, firstImageObject+object)
+		}
+		startObject(pageObject)
+		fmt.Fprintf(&buffer, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> /XObject << %s >> >> /Contents %d 0 R >>\nendobj\n",
+			number(PageWidth), number(PageHeight), fontRegular, fontBold, strings.Join(images, " "), pageObject+1)
+		content, err := compress(page.content.Bytes())
+		if err != nil {
+			return err
+		}
+		startObject(pageObject + 1)
+		fmt.Fprintf(&buffer, "<< /Filter /FlateDecode /Length %d >>\nstream\n", len(content))
+		buffer.Write(content)
+		buffer.WriteString("\nendstream\nendobj\n")
+	}
+	crossReference := buffer.Len()
+	fmt.Fprintf(&buffer, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, offset := range offsets[1:] {
+		fmt.Fprintf(&buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buffer, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), crossReference)
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func getString(text []byte) string {
+	var builder strings.Builder
+	builder.WriteByte('(')
+	for _, character := range text {
+		switch {
+		case character == '(' || character == ')' || character == '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(character)
+		case character < ' ' || character > '~':
+			fmt.Fprintf(&builder, "\\%03o", character)
+		default:
+			builder.WriteByte(character)
+		}
+	}
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+func getColor(value color.Color) string {
+	rgba := color.RGBAModel.Convert(value).(color.RGBA)
+	return fmt.Sprintf("%s %s %s", number(float64(rgba.R)/255), number(float64(rgba.G)/255), number(float64(rgba.B)/255))
+}
+
+func number(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1000)/1000, 'f', -1, 64)
+}
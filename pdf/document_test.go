@@ -0,0 +1,135 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var referencePattern = regexp.MustCompile(`(\d+) 0 R`)
+
+func TestWriteCrossReference(t *testing.T) {
+	document := New("Dashboard (weekly) – €", time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	chart := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for _, imageCount := range []int{0, 1, 3} {
+		page := document.AddPage()
+		page.Text(40, 40, fmt.Sprintf("Page with %d images", imageCount), 12, true, color.Black)
+		page.Rect(40, 60, 100, 20, color.RGBA{R: 0x33, G: 0x66, B: 0x99, A: 0xff})
+		page.Line(40, 90, 200, 90, 0.5, color.Gray{Y: 0x80})
+		for range imageCount {
+			if err := page.Image(chart, 40, 100, 200, 150); err != nil {
+				t.Fatalf("Image: %v", err)
+			}
+		}
+	}
+	var output bytes.Buffer
+	if err := document.Write(&output); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	file := output.Bytes()
+	if !bytes.HasPrefix(file, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(file, []byte("%%EOF\n")) {
+		t.Fatalf("file doesn't start with a PDF header or end with %%%%EOF")
+	}
+
+	trailerStart := bytes.LastIndex(file, []byte("trailer\n"))
+	startxref := bytes.LastIndex(file, []byte("startxref\n"))
+	if trailerStart == -1 || startxref == -1 {
+		t.Fatal("file has no trailer or startxref")
+	}
+	crossReference, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(string(file[startxref+len("startxref\n"):]), "%%EOF\n")))
+	if err != nil || !bytes.HasPrefix(file[crossReference:], []byte("xref\n0 ")) {
+		t.Fatalf("startxref %d (%v) doesn't point at the xref table", crossReference, err)
+	}
+	header, entries, _ := strings.Cut(string(file[crossReference+len("xref\n"):trailerStart]), "\n")
+	var first, count int
+	if _, err := fmt.Sscanf(header, "%d %d", &first, &count); err != nil || first != 0 {
+		t.Fatalf("xref subsection header %q", header)
+	}
+	wantCount := firstImageObject + 4 + 3*2
+	if count != wantCount || len(entries) != count*20 {
+		t.Fatalf("xref has %d entries in %d bytes, want %d entries of 20 bytes", count, len(entries), wantCount)
+	}
+	if !strings.Contains(string(file[trailerStart:]), fmt.Sprintf("/Size %d ", count)) {
+		t.Errorf("trailer %q doesn't have /Size %d", file[trailerStart:startxref], count)
+	}
+	if entries[:20] != "0000000000 65535 f \n" {
+		t.Errorf("xref entry 0 = %q, want the free list head", entries[:20])
+	}
+	for id := 1; id < count; id++ {
+		entry := entries[id*20 : (id+1)*20]
+		if !strings.HasSuffix(entry, " 00000 n \n") {
+			t.Errorf("xref entry %d = %q, want an in-use entry", id, entry)
+			continue
+		}
+		offset, err := strconv.Atoi(entry[:10])
+		if err != nil || offset >= crossReference || !bytes.HasPrefix(file[offset:], fmt.Appendf(nil, "%d 0 obj\n", id)) {
+			t.Errorf("xref entry %d = %q doesn't point at object %d", id, entry, id)
+		}
+	}
+	for _, match := range referencePattern.FindAllSubmatch(file, -1) {
+		if id, _ := strconv.Atoi(string(match[1])); id < 1 || id >= count {
+			t.Errorf("reference %s points at a missing object", match[0])
+		}
+	}
+	if !strings.Contains(string(file), "/Title (Dashboard \\(weekly\\) \\226 \\200)") {
+		t.Error("title isn't escaped and encoded as WinAnsi")
+	}
+	if !strings.Contains(string(file), "/CreationDate (D:20250102030405Z)") {
+		t.Error("creation date is missing")
+	}
+}
+
+func TestPageContent(t *testing.T) {
+	document := New("Test", time.Now())
+	page := document.AddPage()
+	page.Text(10, 20, "Hello (world)", 12, false, color.RGBA{R: 0xff, A: 0xff})
+	page.Rect(10, 30, 50, 5, color.White)
+	page.Image(image.NewRGBA(image.Rect(0, 0, 1, 1)), 0, 0, 10, 10)
+	var output bytes.Buffer
+	if err := document.Write(&output); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	file := output.Bytes()
+	streamStart := bytes.LastIndex(file, []byte(">>\nstream\n")) + len(">>\nstream\n")
+	streamEnd := bytes.LastIndex(file, []byte("\nendstream"))
+	reader, err := zlib.NewReader(bytes.NewReader(file[streamStart:streamEnd]))
+	if err != nil {
+		t.Fatalf("page content isn't zlib compressed: %v", err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "BT 1 0 0 rg /F1 12 Tf 10 821.89 Td (Hello \\(world\\)) Tj ET\n" +
+		"1 1 1 rg 10 806.89 50 5 re f\n" +
+		"q 10 0 0 10 0 831.89 cm /Im0 Do Q\n"
+	if string(content) != want {
+		t.Errorf("page content =\n%s\nwant\n%s", content, want)
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	for _, test := range []struct {
+		text  string
+		bold  bool
+		width float64
+	}{
+		{"", false, 0},
+		{"Hi", false, (722 + 222) * 10.0 / 1000},
+		{"Hi", true, (722 + 278) * 10.0 / 1000},
+		{"–€", false, (556 + 556) * 10.0 / 1000},
+		{"日", false, 556 * 10.0 / 1000},
+	} {
+		if width := TextWidth(test.text, 10, test.bold); width != test.width {
+			t.Errorf("TextWidth(%q, bold %v) = %v, want %v", test.text, test.bold, width, test.width)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package pdf
+
+const defaultCharacterWidth = 556
+
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+var winAnsiCharacters = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+var winAnsiWidths = map[byte]int{
+	0x85: 1000, 0x91: 222, 0x92: 222, 0x93: 333, 0x94: 333, 0x95: 350, 0x96: 556, 0x97: 1000,
+}
+
+func TextWidth(text string, size float64, bold bool) float64 {
+	total := 0
+	for _, character := range encodeText(text) {
+		total += getCharacterWidth(character, bold)
+	}
+	return float64(total) * size / 1000
+}
+
+func getCharacterWidth(character byte, bold bool) int {
+	if character >= ' ' && character <= '~' {
+		if bold {
+			return helveticaBoldWidths[character-' ']
+		}
+		return helveticaWidths[character-' ']
+	}
+	if width, exists := winAnsiWidths[character]; exists {
+		return width
+	}
+	return defaultCharacterWidth
+}
+
+func encodeText(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, character := range text {
+		switch {
+		case character >= ' ' && character <= '~', character >= 0xA0 && character <= 0xFF:
+			encoded = append(encoded, byte(character))
+		case winAnsiCharacters[character] != 0:
+			encoded = append(encoded, winAnsiCharacters[character])
+		default:
+			encoded = append(encoded, '?')
+		}
+	}
+	return encoded
+}
@@ -0,0 +1,98 @@
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+func NewGauge(name string, help string, value float64) Metric {
+	return Metric{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: value}}}
+}
+
+func (m *Metric) Add(value float64, labels ...string) {
+	sample := Sample{Labels: make(map[string]string), Value: value}
+	for index := 0; index+1 < len(labels); index += 2 {
+		sample.Labels[labels[index]] = labels[index+1]
+	}
+	m.Samples = append(m.Samples, sample)
+}
+
+func Write(writer io.Writer, metrics []Metric) error {
+	buffered := bufio.NewWriter(writer)
+	for _, metric := range metrics {
+		if !namePattern.MatchString(metric.Name) {
+			return fmt.Errorf("invalid metric name %q", metric.Name)
+		}
+		fmt.Fprintf(buffered, "# HELP %s %s\n", metric.Name, helpReplacer.Replace(metric.Help))
+		fmt.Fprintf(buffered, "# TYPE %s %s\n", metric.Name, metric.Type)
+		for _, sample := range metric.Samples {
+			labels, err := formatLabels(sample.Labels)
+			if err != nil {
+				return fmt.Errorf("metric %q: %w", metric.Name, err)
+			}
+			fmt.Fprintf(buffered, "%s%s %s\n", metric.Name, labels, formatValue(sample.Value))
+		}
+	}
+	return buffered.Flush()
+}
+
+func formatLabels(labels map[string]string) (string, error) {
+	if len(labels) == 0 {
+		return "", nil
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if !labelNamePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid label name %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for index, name := range names {
+		pairs[index] = fmt.Sprintf(`%s="%s"`, name, labelValueReplacer.Replace(labels[name]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}", nil
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
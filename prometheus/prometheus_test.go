@@ -0,0 +1,86 @@
+package prometheus
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	users := Metric{Name: "fusionauth_users", Help: "Users by verification.", Type: TypeGauge}
+	users.Add(3, "verified", "true")
+	users.Add(1, "verified", "false")
+	alerts := Metric{Name: "fusionauth_alert_firing", Help: "Firing alerts.\nOne sample per rule, C:\\rules.", Type: TypeGauge}
+	alerts.Add(1, "rule", "Say \"hi\"\nback\\slash", "bucket", "a")
+	events := Metric{Name: "fusionauth_webhook_events_total", Help: "Events.", Type: TypeCounter}
+	events.Add(12345678)
+	var output bytes.Buffer
+	err := Write(&output, []Metric{
+		users,
+		alerts,
+		events,
+		NewGauge("fusionauth_dau_mau_ratio", "Ratio.", math.NaN()),
+		NewGauge("fusionauth_data_age_seconds", "Age.", 0.25),
+		NewGauge("fusionauth_positive", "Positive infinity.", math.Inf(1)),
+		NewGauge("fusionauth_negative", "Negative infinity.", math.Inf(-1)),
+		NewGauge("fusionauth_timestamp_seconds", "Timestamp.", 1735732800.5),
+		{Name: "fusionauth_empty", Help: "No samples.", Type: TypeGauge},
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := `# HELP fusionauth_users Users by verification.
+# TYPE fusionauth_users gauge
+fusionauth_users{verified="true"} 3
+fusionauth_users{verified="false"} 1
+# HELP fusionauth_alert_firing Firing alerts.\nOne sample per rule, C:\\rules.
+# TYPE fusionauth_alert_firing gauge
+fusionauth_alert_firing{bucket="a",rule="Say \"hi\"\nback\\slash"} 1
+# HELP fusionauth_webhook_events_total Events.
+# TYPE fusionauth_webhook_events_total counter
+fusionauth_webhook_events_total 1.2345678e+07
+# HELP fusionauth_dau_mau_ratio Ratio.
+# TYPE fusionauth_dau_mau_ratio gauge
+fusionauth_dau_mau_ratio NaN
+# HELP fusionauth_data_age_seconds Age.
+# TYPE fusionauth_data_age_seconds gauge
+fusionauth_data_age_seconds 0.25
+# HELP fusionauth_positive Positive infinity.
+# TYPE fusionauth_positive gauge
+fusionauth_positive +Inf
+# HELP fusionauth_negative Negative infinity.
+# TYPE fusionauth_negative gauge
+fusionauth_negative -Inf
+# HELP fusionauth_timestamp_seconds Timestamp.
+# TYPE fusionauth_timestamp_seconds gauge
+fusionauth_timestamp_seconds 1.7357328005e+09
+# HELP fusionauth_empty No samples.
+# TYPE fusionauth_empty gauge
+`
+	if output.String() != want {
+		t.Errorf("Write =\n%s\nwant\n%s", output.String(), want)
+	}
+}
+
+func TestWriteRejectsInvalidNames(t *testing.T) {
+	withLabel := func(name string) Metric {
+		metric := Metric{Name: "fusionauth_users", Type: TypeGauge}
+		metric.Add(1, name, "value")
+		return metric
+	}
+	for _, test := range []struct {
+		metric Metric
+		want   string
+	}{
+		{NewGauge("fusionauth-users", "", 1), `invalid metric name "fusionauth-users"`},
+		{NewGauge("1users", "", 1), `invalid metric name "1users"`},
+		{withLabel("a:b"), `metric "fusionauth_users": invalid label name "a:b"`},
+		{withLabel(""), `metric "fusionauth_users": invalid label name ""`},
+	} {
+		err := Write(&bytes.Buffer{}, []Metric{test.metric})
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Write(%+v) = %v, want %s", test.metric, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package report
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"app/digest"
+	"app/pdf"
+)
+
+const margin = 50
+const footerHeight = 30
+const contentWidth = pdf.PageWidth - 2*margin
+const contentBottom = pdf.PageHeight - margin - footerHeight
+const titleSize = 22
+const headingSize = 13
+const textSize = 9.5
+const tableTextSize = 8
+const tableRowHeight = 14
+const cellPadding = 4
+const lineSpacing = 1.35
+const sectionGap = 24
+const chartGuideHeading = "Chart guide"
+
+var textColor = color.RGBA{51, 51, 51, 255}
+var mutedColor = color.RGBA{110, 110, 110, 255}
+var ruleColor = color.RGBA{204, 204, 204, 255}
+var headerFill = color.RGBA{240, 240, 240, 255}
+
+var headingPattern = regexp.MustCompile(`^#+\s+(.*)$`)
+var guideEntryPattern = regexp.MustCompile("^- `([^`]+)` — (.*)$")
+var linkPattern = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+
+type Report struct {
+	Title       string
+	GeneratedAt time.Time
+	ExtractedAt time.Time
+	Filters     string
+	WindowDays  int
+	Kpis        []digest.Kpi
+	Sections    []Section
+}
+
+type Section struct {
+	Title       string
+	Description string
+	Image       image.Image
+	Table       *Table
+}
+
+type Table struct {
+	Columns   []string
+	Rows      [][]string
+	TotalRows int
+}
+
+type layout struct {
+	document *pdf.Document
+	page     *pdf.Page
+	y        float64
+}
+
+func Write(writer io.Writer, report Report) error {
+	document := pdf.New(report.Title, report.GeneratedAt)
+	layout := &layout{document: document}
+	layout.newPage()
+	layout.paragraph(report.Title, titleSize, true, textColor)
+	layout.y += 6
+	layout.paragraph("Generated "+report.GeneratedAt.Format("2006-01-02 15:04 MST"), textSize, false, mutedColor)
+	if report.ExtractedAt.IsZero() {
+		layout.paragraph("No data loaded", textSize, false, mutedColor)
+	} else {
+		layout.paragraph("Data extracted "+report.ExtractedAt.Format("2006-01-02 15:04 MST"), textSize, false, mutedColor)
+	}
+	if report.Filters != "" {
+		layout.paragraph("Charts filtered by "+report.Filters, textSize, false, mutedColor)
+	}
+	layout.y += sectionGap
+	layout.paragraph("Key numbers", headingSize, true, textColor)
+	summary := fmt.Sprintf("The last %d days compared with the %d days before, for all users.", report.WindowDays, report.WindowDays)
+	layout.paragraph(summary, textSize, false, mutedColor)
+	layout.y += 4
+	kpiRows := make([][]string, len(report.Kpis))
+	for index, kpi := range report.Kpis {
+		kpiRows[index] = []string{kpi.Name, digest.FormatValue(kpi.Current, kpi.Unit), "", ""}
+		if kpi.HasPrevious {
+			kpiRows[index][2], kpiRows[index][3] = digest.FormatValue(kpi.Previous, kpi.Unit), digest.FormatChange(kpi)
+		}
+	}
+	layout.table(Table{Columns: []string{"Measure", "Now", "Before", "Change"}, Rows: kpiRows, TotalRows: len(kpiRows)})
+	for _, section := range report.Sections {
+		if err := layout.section(section); err != nil {
+			return err
+		}
+	}
+	pages := document.Pages()
+	for index, page := range pages {
+		footer := fmt.Sprintf("%s — page %d of %d", report.Title, index+1, len(pages))
+		page.Line(margin, pdf.PageHeight-margin-footerHeight/2, pdf.PageWidth-margin, pdf.PageHeight-margin-footerHeight/2, 0.5, ruleColor)
+		page.Text(margin, pdf.PageHeight-margin, footer, tableTextSize, false, mutedColor)
+	}
+	return document.Write(writer)
+}
+
+func ParseChartGuide(markdown string) map[string]string {
+	descriptions := make(map[string]string)
+	isInGuide := false
+	for _, line := range strings.Split(markdown, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if heading := headingPattern.FindStringSubmatch(line); heading != nil {
+			isInGuide = strings.TrimSpace(heading[1]) == chartGuideHeading
+			continue
+		}
+		if entry := guideEntryPattern.FindStringSubmatch(line); isInGuide && entry != nil {
+			descriptions[entry[1]] = stripMarkdown(entry[2])
+		}
+	}
+	return descriptions
+}
+
+func stripMarkdown(text string) string {
+	text = linkPattern.ReplaceAllString(text, "$1")
+	return strings.NewReplacer("`", "", "**", "", "*", "").Replace(text)
+}
+
+func (l *layout) newPage() {
+	l.page = l.document.AddPage()
+	l.y = margin
+}
+
+func (l *layout) ensure(height float64) {
+	if l.y+height > contentBottom && l.y > margin {
+		l.newPage()
+	}
+}
+
+func (l *layout) paragraph(text string, size float64, bold bool, fill color.Color) {
+	for _, line := range wrap(text, size, bold, contentWidth) {
+		l.ensure(size * lineSpacing)
+		l.y += size
+		l.page.Text(margin, l.y, line, size, bold, fill)
+		l.y += size * (lineSpacing - 1)
+	}
+}
+
+func (l *layout) section(section Section) error {
+	descriptionLines := 0
+	if section.Description != "" {
+		descriptionLines = len(wrap(section.Description, textSize, false, contentWidth))
+	}
+	headerHeight := headingSize*lineSpacing + float64(descriptionLines)*textSize*lineSpacing + 6
+	width, height := 0.0, 0.0
+	if section.Image != nil {
+		bounds := section.Image.Bounds()
+		width = contentWidth
+		height = width * float64(bounds.Dy()) / float64(bounds.Dx())
+		if maxHeight := contentBottom - margin - headerHeight; height > maxHeight {
+			height = maxHeight
+			width = height * float64(bounds.Dx()) / float64(bounds.Dy())
+		}
+		l.ensure(sectionGap + headerHeight + height)
+	} else {
+		l.ensure(sectionGap + headerHeight + 3*tableRowHeight)
+	}
+	if l.y > margin {
+		l.y += sectionGap
+	}
+	l.paragraph(section.Title, headingSize, true, textColor)
+	if section.Description != "" {
+		l.paragraph(section.Description, textSize, false, mutedColor)
+	}
+	l.y += 6
+	if section.Image != nil {
+		if err := l.page.Image(section.Image, margin+(contentWidth-width)/2, l.y, width, height); err != nil {
+			return err
+		}
+		l.y += height
+	}
+	if section.Table != nil {
+		l.table(*section.Table)
+	}
+	return nil
+}
+
+func (l *layout) table(table Table) {
+	if len(table.Columns) == 0 {
+		return
+	}
+	columnWidth := contentWidth / float64(len(table.Columns))
+	header := func() {
+		l.page.Rect(margin, l.y, contentWidth, tableRowHeight, headerFill)
+		l.row(table.Columns, columnWidth, true)
+	}
+	l.ensure(2 * tableRowHeight)
+	header()
+	if len(table.Rows) == 0 {
+		l.row([]string{"No data"}, contentWidth, false)
+	}
+	for _, row := range table.Rows {
+		if l.y+tableRowHeight > contentBottom {
+			l.newPage()
+			header()
+		}
+		l.row(row, columnWidth, false)
+	}
+	if table.TotalRows > len(table.Rows) {
+		l.y += 4
+		l.paragraph(fmt.Sprintf("Showing the first %d of %d rows.", len(table.Rows), table.TotalRows), tableTextSize, false, mutedColor)
+	}
+}
+
+func (l *layout) row(cells []string, columnWidth float64, bold bool) {
+	for index, cell := range cells {
+		text := shorten(cell, tableTextSize, bold, columnWidth-2*cellPadding)
+		l.page.Text(margin+float64(index)*columnWidth+cellPadding, l.y+tableRowHeight-cellPadding, text, tableTextSize, bold, textColor)
+	}
+	l.y += tableRowHeight
+	l.page.Line(margin, l.y, margin+contentWidth, l.y, 0.5, ruleColor)
+}
+
+func wrap(text string, size float64, bold bool, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line != "" && pdf.TextWidth(candidate, size, bold) > width {
+			lines = append(lines, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func shorten(text string, size float64, bold bool, width float64) string {
+	if pdf.TextWidth(text, size, bold) <= width {
+		return text
+	}
+	characters := []rune(text)
+	for len(characters) > 0 && pdf.TextWidth(string(characters)+"…", size, bold) > width {
+		characters = characters[:len(characters)-1]
+	}
+	return string(characters) + "…"
+}
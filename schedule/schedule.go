@@ -0,0 +1,144 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxSearchMinutes = 5 * 366 * 24 * 60
+
+var shortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+type field struct {
+	name string
+	min  int
+	max  int
+}
+
+var fields = []field{{"minute", 0, 59}, {"hour", 0, 23}, {"day of month", 1, 31}, {"month", 1, 12}, {"day of week", 0, 6}}
+
+type Schedule struct {
+	source  string
+	every   time.Duration
+	allowed [5]map[int]bool
+	anyDay  [2]bool
+}
+
+func Parse(source string) (Schedule, error) {
+	schedule := Schedule{source: source}
+	text := strings.TrimSpace(source)
+	if after, found := strings.CutPrefix(text, "@every "); found {
+		every, err := time.ParseDuration(strings.TrimSpace(after))
+		if err != nil || every < time.Minute {
+			return schedule, fmt.Errorf("schedule %q: @every needs a duration of at least 1m, like 15m or 2h", source)
+		}
+		schedule.every = every
+		return schedule, nil
+	}
+	if expanded, exists := shortcuts[text]; exists {
+		text = expanded
+	}
+	parts := strings.Fields(text)
+	if len(parts) != len(fields) {
+		return schedule, fmt.Errorf("schedule %q: expected 5 fields (minute hour day-of-month month day-of-week), @hourly, @daily, @weekly, @monthly, or @every <duration>", source)
+	}
+	for index, part := range parts {
+		allowed, err := parseField(part, fields[index])
+		if err != nil {
+			return schedule, fmt.Errorf("schedule %q: %w", source, err)
+		}
+		schedule.allowed[index] = allowed
+	}
+	schedule.anyDay = [2]bool{parts[2] == "*", parts[4] == "*"}
+	if schedule.Next(time.Now()).IsZero() {
+		return schedule, fmt.Errorf("schedule %q never runs", source)
+	}
+	return schedule, nil
+}
+
+func (s Schedule) String() string {
+	return s.source
+}
+
+func (s Schedule) Next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Add(s.every)
+	}
+	next := after.Truncate(time.Minute).Add(time.Minute)
+	for range maxSearchMinutes {
+		if s.matches(next) {
+			return next
+		}
+		next = next.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+func (s Schedule) matches(t time.Time) bool {
+	if !s.allowed[0][t.Minute()] || !s.allowed[1][t.Hour()] || !s.allowed[3][int(t.Month())] {
+		return false
+	}
+	dayOfMonth, dayOfWeek := s.allowed[2][t.Day()], s.allowed[4][int(t.Weekday())]
+	switch {
+	case s.anyDay[0] && s.anyDay[1]:
+		return true
+	case s.anyDay[0]:
+		return dayOfWeek
+	case s.anyDay[1]:
+		return dayOfMonth
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+func parseField(text string, f field) (map[int]bool, error) {
+	allowed := make(map[int]bool)
+	for _, item := range strings.Split(text, ",") {
+		rangeText, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepText)
+			if err != nil || parsed < 1 {
+				return nil, fmt.Errorf("%s step %q must be a positive number", f.name, stepText)
+			}
+			step = parsed
+		}
+		low, high := f.min, f.max
+		if rangeText != "*" {
+			lowText, highText, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if low, err = parseValue(lowText, f); err != nil {
+				return nil, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseValue(highText, f); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				high = f.max
+			}
+			if high < low {
+				return nil, fmt.Errorf("%s range %q is backwards", f.name, rangeText)
+			}
+		}
+		for value := low; value <= high; value += step {
+			allowed[value] = true
+		}
+	}
+	return allowed, nil
+}
+
+func parseValue(text string, f field) (int, error) {
+	value, err := strconv.Atoi(text)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("%s %q must be a number from %d to %d", f.name, text, f.min, f.max)
+	}
+	return value, nil
+}
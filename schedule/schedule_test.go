@@ -0,0 +1,38 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRejectsInvalidSchedules(t *testing.T) {
+	for _, source := range []string{"", "* * * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *", "@every 30s", "@yearly", "0 0 31 2 *", "0 0 30,31 2 *"} {
+		if _, err := Parse(source); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", source)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	after := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC)
+	for _, test := range []struct {
+		source string
+		want   time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 8 * * 1", time.Date(2024, time.February, 5, 8, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2024, time.January, 31, 11, 47, 30, 0, time.UTC)},
+	} {
+		schedule, err := Parse(test.source)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", test.source, err)
+		}
+		if got := schedule.Next(after); !got.Equal(test.want) {
+			t.Errorf("Parse(%q).Next(%s) = %s, want %s", test.source, after, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package security
+
+import (
+	_ "embed"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+//go:embed disposableDomains.txt
+var disposableDomainsFile string
+
+var disposableDomains = getDisposableDomains()
+
+const (
+	BotReasonSequentialEmail  = "sequentialEmail"
+	BotReasonRandomEmail      = "randomEmail"
+	BotReasonDisposableDomain = "disposableDomain"
+	BotReasonClusteredSignup  = "clusteredSignup"
+	BotReasonNeverVerified    = "neverVerified"
+	BotReasonNeverLoggedIn    = "neverLoggedIn"
+)
+
+const maxConsonantRunForHumanNames = 7
+const maxSequenceGap = 2
+
+type Account struct {
+	Id             string
+	Email          string
+	IsVerified     bool
+	RegisteredDate time.Time
+	LoginCount     int
+}
+
+type BotOptions struct {
+	Threshold         float64
+	SequenceMinSize   int
+	ClusterWindow     time.Duration
+	ClusterMinSize    int
+	Weights           map[string]float64
+	DisposableDomains map[string]bool
+}
+
+type BotScore struct {
+	Id      string   `json:"id"`
+	Email   string   `json:"email"`
+	Score   float64  `json:"score"`
+	IsBot   bool     `json:"isBot"`
+	Reasons []string `json:"reasons"`
+}
+
+func DefaultBotOptions() BotOptions {
+	return BotOptions{
+		Threshold:       0.5,
+		SequenceMinSize: 3,
+		ClusterWindow:   10 * time.Second,
+		ClusterMinSize:  5,
+		Weights: map[string]float64{
+			BotReasonSequentialEmail:  0.35,
+			BotReasonRandomEmail:      0.25,
+			BotReasonDisposableDomain: 0.4,
+			BotReasonClusteredSignup:  0.25,
+			BotReasonNeverVerified:    0.15,
+			BotReasonNeverLoggedIn:    0.15,
+		},
+		DisposableDomains: disposableDomains,
+	}
+}
+
+func ScoreBots(accounts []Account, options BotOptions) []BotScore {
+	sequential := getSequentialAccounts(accounts, options.SequenceMinSize)
+	clustered := getClusteredAccounts(accounts, options.ClusterWindow, options.ClusterMinSize)
+	scores := make([]BotScore, 0, len(accounts))
+	for index, account := range accounts {
+		scores = append(scores, scoreAccount(account, sequential[index], clustered[index], options))
+	}
+	return scores
+}
+
+func RescoreBot(previous BotScore, account Account, options BotOptions) BotScore {
+	return scoreAccount(account, slices.Contains(previous.Reasons, BotReasonSequentialEmail), slices.Contains(previous.Reasons, BotReasonClusteredSignup), options)
+}
+
+func scoreAccount(account Account, sequential bool, clustered bool, options BotOptions) BotScore {
+	localPart, domain := splitEmail(account.Email)
+	reasons := []string{}
+	if sequential {
+		reasons = append(reasons, BotReasonSequentialEmail)
+	}
+	if isRandomLooking(localPart) {
+		reasons = append(reasons, BotReasonRandomEmail)
+	}
+	if options.DisposableDomains[domain] {
+		reasons = append(reasons, BotReasonDisposableDomain)
+	}
+	if clustered {
+		reasons = append(reasons, BotReasonClusteredSignup)
+	}
+	if !account.IsVerified {
+		reasons = append(reasons, BotReasonNeverVerified)
+	}
+	if account.LoginCount == 0 {
+		reasons = append(reasons, BotReasonNeverLoggedIn)
+	}
+	score := 0.0
+	for _, reason := range reasons {
+		score += options.Weights[reason]
+	}
+	score = min(score, 1)
+	return BotScore{Id: account.Id, Email: account.Email, Score: score, IsBot: score >= options.Threshold, Reasons: reasons}
+}
+
+func getSequentialAccounts(accounts []Account, minSize int) map[int]bool {
+	groups := make(map[string][]int)
+	numbers := make(map[int]int64)
+	for index, account := range accounts {
+		localPart, domain := splitEmail(account.Email)
+		prefix := strings.TrimRightFunc(localPart, unicode.IsDigit)
+		if prefix == localPart {
+			continue
+		}
+		number, err := strconv.ParseInt(localPart[len(prefix):], 10, 64)
+		if err != nil {
+			continue
+		}
+		key := prefix + "@" + domain
+		groups[key] = append(groups[key], index)
+		numbers[index] = number
+	}
+	sequential := make(map[int]bool)
+	for _, indexes := range groups {
+		if len(indexes) < max(minSize, 2) {
+			continue
+		}
+		sort.Slice(indexes, func(i, j int) bool { return numbers[indexes[i]] < numbers[indexes[j]] })
+		start := 0
+		for end := range indexes {
+			if end > 0 && numbers[indexes[end]]-numbers[indexes[end-1]] > maxSequenceGap {
+				start = end
+			}
+			if end-start+1 >= max(minSize, 2) {
+				for _, index := range indexes[start : end+1] {
+					sequential[index] = true
+				}
+			}
+		}
+	}
+	return sequential
+}
+
+func getClusteredAccounts(accounts []Account, window time.Duration, minSize int) map[int]bool {
+	clustered := make(map[int]bool)
+	if minSize <= 1 {
+		return clustered
+	}
+	indexes := make([]int, len(accounts))
+	for index := range indexes {
+		indexes[index] = index
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return accounts[indexes[i]].RegisteredDate.Before(accounts[indexes[j]].RegisteredDate)
+	})
+	start := 0
+	for end := range indexes {
+		for accounts[indexes[end]].RegisteredDate.Sub(accounts[indexes[start]].RegisteredDate) > window {
+			start++
+		}
+		if end-start+1 >= minSize {
+			for _, index := range indexes[start : end+1] {
+				clustered[index] = true
+			}
+		}
+	}
+	return clustered
+}
+
+func isRandomLooking(localPart string) bool {
+	letters := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, localPart)
+	if len(localPart) < 8 || len(letters) == 0 {
+		return false
+	}
+	transitions, consonantRun, longestConsonantRun := 0, 0, 0
+	previous := rune(-1)
+	for _, r := range localPart {
+		if previous != -1 && unicode.IsDigit(r) != unicode.IsDigit(previous) {
+			transitions++
+		}
+		previous = r
+		if unicode.IsLetter(r) && !strings.ContainsRune("aeiouy", unicode.ToLower(r)) {
+			consonantRun++
+			longestConsonantRun = max(longestConsonantRun, consonantRun)
+		} else {
+			consonantRun = 0
+		}
+	}
+	return transitions >= 3 || longestConsonantRun > maxConsonantRunForHumanNames
+}
+
+func splitEmail(email string) (string, string) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return email, ""
+	}
+	return email[:at], email[at+1:]
+}
+
+func getDisposableDomains() map[string]bool {
+	domains := make(map[string]bool)
+	for _, line := range strings.Split(disposableDomainsFile, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			domains[strings.ToLower(line)] = true
+		}
+	}
+	return domains
+}
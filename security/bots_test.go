@@ -0,0 +1,93 @@
+package security
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSequentialEmails(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		emails     []string
+		sequential []string
+	}{
+		{"consecutive", []string{"user1@example.com", "user2@example.com", "user3@example.com", "user10@example.com"}, []string{"user1@example.com", "user2@example.com", "user3@example.com"}},
+		{"near-consecutive and unordered", []string{"bot7@example.com", "bot3@example.com", "bot5@example.com"}, []string{"bot3@example.com", "bot5@example.com", "bot7@example.com"}},
+		{"leading zeros", []string{"test001@example.com", "test002@example.com", "test003@example.com"}, []string{"test001@example.com", "test002@example.com", "test003@example.com"}},
+		{"numbers only", []string{"1@example.com", "2@example.com", "3@example.com"}, []string{"1@example.com", "2@example.com", "3@example.com"}},
+		{"unrelated numbers", []string{"john1@x.com", "john1985@x.com", "john77@x.com"}, []string{}},
+		{"too short a run", []string{"jane1@x.com", "jane2@x.com", "jane40@x.com", "jane41@x.com"}, []string{}},
+		{"different domains", []string{"user1@a.com", "user2@b.com", "user3@c.com"}, []string{}},
+	} {
+		accounts := make([]Account, len(test.emails))
+		for index, email := range test.emails {
+			accounts[index] = Account{Id: email, Email: email}
+		}
+		sequential := getSequentialAccounts(accounts, DefaultBotOptions().SequenceMinSize)
+		got := []string{}
+		for index, account := range accounts {
+			if sequential[index] {
+				got = append(got, account.Email)
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, test.sequential) {
+			t.Errorf("%s: sequential = %v, want %v", test.name, got, test.sequential)
+		}
+	}
+}
+
+func TestUnrelatedNumberedEmailsAreNotBots(t *testing.T) {
+	accounts := []Account{
+		{Id: "1", Email: "john1@x.com", IsVerified: true},
+		{Id: "2", Email: "john1985@x.com", IsVerified: true},
+		{Id: "3", Email: "john77@x.com", IsVerified: true},
+	}
+	for _, score := range ScoreBots(accounts, DefaultBotOptions()) {
+		if score.IsBot || slices.Contains(score.Reasons, BotReasonSequentialEmail) {
+			t.Errorf("%s scored %+v, want no sequential email and not a bot", score.Email, score)
+		}
+	}
+}
+
+func TestRescoreBot(t *testing.T) {
+	options := DefaultBotOptions()
+	accounts := []Account{{Id: "1", Email: "user1@example.com"}, {Id: "2", Email: "user2@example.com"}, {Id: "3", Email: "user3@example.com"}}
+	scores := ScoreBots(accounts, options)
+	if !scores[1].IsBot {
+		t.Fatalf("unverified sequential account scored %+v, want a bot", scores[1])
+	}
+	accounts[1].IsVerified, accounts[1].LoginCount = true, 1
+	rescored := RescoreBot(scores[1], accounts[1], options)
+	if want := ScoreBots(accounts, options)[1]; !reflect.DeepEqual(rescored, want) {
+		t.Errorf("RescoreBot = %+v, want %+v", rescored, want)
+	}
+	if !slices.Equal(rescored.Reasons, []string{BotReasonSequentialEmail}) || rescored.IsBot {
+		t.Errorf("RescoreBot = %+v, want only the sequential email reason kept", rescored)
+	}
+}
+
+func TestRandomLookingLocalParts(t *testing.T) {
+	for _, test := range []struct {
+		localPart string
+		random    bool
+	}{
+		{"schmidt", false},
+		{"hans.schmidt", false},
+		{"jschmidt", false},
+		{"mschwartz", false},
+		{"strzelecki", false},
+		{"anna.strzelecki", false},
+		{"chrzczonowicz", false},
+		{"hirschsprung", false},
+		{"john.smith1985", false},
+		{"xkqzvbtrwp", true},
+		{"a8f3k2m9x1", true},
+		{"qwrtzpsdfgh", true},
+	} {
+		if random := isRandomLooking(test.localPart); random != test.random {
+			t.Errorf("isRandomLooking(%q) = %v, want %v", test.localPart, random, test.random)
+		}
+	}
+}
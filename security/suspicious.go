@@ -0,0 +1,159 @@
+package security
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const earthRadiusKm = 6371.0
+
+const (
+	FindingImpossibleTravel = "impossibleTravel"
+	FindingLoginBurst       = "loginBurst"
+	FindingManyIps          = "manyIps"
+)
+
+type Login struct {
+	Time             time.Time `json:"time"`
+	IpAddress        string    `json:"ipAddress"`
+	Country          string    `json:"country"`
+	Latitude         float64   `json:"latitude,omitempty"`
+	Longitude        float64   `json:"longitude,omitempty"`
+	AccuracyRadiusKm float64   `json:"accuracyRadiusKm,omitempty"`
+	HasCoordinates   bool      `json:"hasCoordinates"`
+}
+
+type Finding struct {
+	Type           string    `json:"type"`
+	Description    string    `json:"description"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	DistanceKm     float64   `json:"distanceKm,omitempty"`
+	SpeedKmPerHour float64   `json:"speedKmPerHour,omitempty"`
+	Logins         []Login   `json:"logins"`
+}
+
+type Options struct {
+	MaxSpeedKmPerHour   float64
+	MinTravelDistanceKm float64
+	BurstCount          int
+	BurstWindow         time.Duration
+	DistinctIpCount     int
+	DistinctIpWindow    time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxSpeedKmPerHour:   1000,
+		MinTravelDistanceKm: 500,
+		BurstCount:          5,
+		BurstWindow:         10 * time.Minute,
+		DistinctIpCount:     5,
+		DistinctIpWindow:    24 * time.Hour,
+	}
+}
+
+func DetectSuspiciousLogins(logins []Login, options Options) []Finding {
+	findings := []Finding{}
+	findings = append(findings, detectImpossibleTravel(logins, options)...)
+	findings = append(findings, detectLoginBursts(logins, options)...)
+	findings = append(findings, detectManyIps(logins, options)...)
+	return findings
+}
+
+func detectImpossibleTravel(logins []Login, options Options) []Finding {
+	findings := []Finding{}
+	var previous *Login
+	for index := range logins {
+		login := &logins[index]
+		if !login.HasCoordinates {
+			continue
+		}
+		if previous != nil && previous.IpAddress != login.IpAddress {
+			distance := getDistanceKm(*previous, *login) - previous.AccuracyRadiusKm - login.AccuracyRadiusKm
+			hours := max(login.Time.Sub(previous.Time).Hours(), 1.0/60)
+			speed := distance / hours
+			if distance >= options.MinTravelDistanceKm && speed > options.MaxSpeedKmPerHour {
+				findings = append(findings, Finding{
+					Type:           FindingImpossibleTravel,
+					Description:    fmt.Sprintf("%.0f km from %s to %s in %s (%.0f km/h)", distance, previous.Country, login.Country, login.Time.Sub(previous.Time).Round(time.Minute), speed),
+					Start:          previous.Time,
+					End:            login.Time,
+					DistanceKm:     math.Round(distance),
+					SpeedKmPerHour: math.Round(speed),
+					Logins:         []Login{*previous, *login},
+				})
+			}
+		}
+		previous = login
+	}
+	return findings
+}
+
+func detectLoginBursts(logins []Login, options Options) []Finding {
+	findings := []Finding{}
+	if options.BurstCount <= 1 {
+		return findings
+	}
+	start := 0
+	for start < len(logins) {
+		end := start
+		for end+1 < len(logins) && logins[end+1].Time.Sub(logins[start].Time) <= options.BurstWindow {
+			end++
+		}
+		count := end - start + 1
+		if count < options.BurstCount {
+			start++
+			continue
+		}
+		findings = append(findings, Finding{
+			Type:        FindingLoginBurst,
+			Description: fmt.Sprintf("%d logins in %s", count, logins[end].Time.Sub(logins[start].Time).Round(time.Second)),
+			Start:       logins[start].Time,
+			End:         logins[end].Time,
+			Logins:      append([]Login{}, logins[start:end+1]...),
+		})
+		start = end + 1
+	}
+	return findings
+}
+
+func detectManyIps(logins []Login, options Options) []Finding {
+	findings := []Finding{}
+	if options.DistinctIpCount <= 1 {
+		return findings
+	}
+	start := 0
+	for start < len(logins) {
+		ipAddresses := make(map[string]bool)
+		end := start
+		for ; end < len(logins) && logins[end].Time.Sub(logins[start].Time) <= options.DistinctIpWindow; end++ {
+			if logins[end].IpAddress != "" {
+				ipAddresses[logins[end].IpAddress] = true
+			}
+		}
+		if len(ipAddresses) < options.DistinctIpCount {
+			start++
+			continue
+		}
+		findings = append(findings, Finding{
+			Type:        FindingManyIps,
+			Description: fmt.Sprintf("%d distinct IP addresses in %s", len(ipAddresses), logins[end-1].Time.Sub(logins[start].Time).Round(time.Minute)),
+			Start:       logins[start].Time,
+			End:         logins[end-1].Time,
+			Logins:      append([]Login{}, logins[start:end]...),
+		})
+		start = end
+	}
+	return findings
+}
+
+func getDistanceKm(from Login, to Login) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	latitudeDifference := toRadians(to.Latitude - from.Latitude)
+	longitudeDifference := toRadians(to.Longitude - from.Longitude)
+	a := math.Sin(latitudeDifference/2)*math.Sin(latitudeDifference/2) +
+		math.Cos(toRadians(from.Latitude))*math.Cos(toRadians(to.Latitude))*math.Sin(longitudeDifference/2)*math.Sin(longitudeDifference/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
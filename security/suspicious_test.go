@@ -0,0 +1,125 @@
+package security
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func located(minutes float64, ipAddress string, country string, longitude float64, accuracyRadiusKm float64) Login {
+	return Login{Time: start.Add(time.Duration(minutes * float64(time.Minute))), IpAddress: ipAddress, Country: country, Longitude: longitude, AccuracyRadiusKm: accuracyRadiusKm, HasCoordinates: true}
+}
+
+func unlocated(minutes float64, ipAddress string) Login {
+	return Login{Time: start.Add(time.Duration(minutes * float64(time.Minute))), IpAddress: ipAddress, Country: "Unknown"}
+}
+
+func TestDetectImpossibleTravel(t *testing.T) {
+	equatorKmPerDegree := earthRadiusKm * math.Pi / 180
+	options := DefaultOptions()
+	for _, test := range []struct {
+		name     string
+		logins   []Login
+		findings []Finding
+	}{
+		{"too fast", []Login{located(0, "a", "Gabon", 0, 0), located(60, "b", "Kenya", 9, 0)}, []Finding{{
+			Type:           FindingImpossibleTravel,
+			Description:    "1001 km from Gabon to Kenya in 1h0m0s (1001 km/h)",
+			Start:          start,
+			End:            start.Add(time.Hour),
+			DistanceKm:     1001,
+			SpeedKmPerHour: 1001,
+			Logins:         []Login{located(0, "a", "Gabon", 0, 0), located(60, "b", "Kenya", 9, 0)},
+		}}},
+		{"just slow enough", []Login{located(0, "a", "Gabon", 0, 0), located(60.05, "b", "Kenya", 9, 0)}, []Finding{}},
+		{"too short a distance", []Login{located(0, "a", "Gabon", 0, 0), located(1, "b", "Congo", 499/equatorKmPerDegree, 0)}, []Finding{}},
+		{"far apart only without the accuracy radius", []Login{located(0, "a", "Gabon", 0, 50), located(1, "b", "Congo", 550/equatorKmPerDegree, 50)}, []Finding{}},
+		{"same IP address", []Login{located(0, "a", "Gabon", 0, 0), located(60, "a", "Kenya", 9, 0)}, []Finding{}},
+		{"login without a location", []Login{located(0, "a", "Gabon", 100, 0), unlocated(10, "b"), located(20, "c", "Gabon", 100, 0)}, []Finding{}},
+		{"skips logins without a location", []Login{located(0, "a", "Gabon", 0, 0), unlocated(10, "b"), located(60, "c", "Kenya", 9, 0)}, []Finding{{
+			Type:           FindingImpossibleTravel,
+			Description:    "1001 km from Gabon to Kenya in 1h0m0s (1001 km/h)",
+			Start:          start,
+			End:            start.Add(time.Hour),
+			DistanceKm:     1001,
+			SpeedKmPerHour: 1001,
+			Logins:         []Login{located(0, "a", "Gabon", 0, 0), located(60, "c", "Kenya", 9, 0)},
+		}}},
+		{"no logins", nil, []Finding{}},
+	} {
+		if findings := detectImpossibleTravel(test.logins, options); !reflect.DeepEqual(findings, test.findings) {
+			t.Errorf("%s: findings = %+v, want %+v", test.name, findings, test.findings)
+		}
+	}
+}
+
+func TestDetectLoginBursts(t *testing.T) {
+	logins := func(minutes ...float64) []Login {
+		result := []Login{}
+		for index, minute := range minutes {
+			result = append(result, unlocated(minute, "ip"+strconv.Itoa(index)))
+		}
+		return result
+	}
+	burst := func(description string, burstLogins []Login) Finding {
+		return Finding{Type: FindingLoginBurst, Description: description, Start: burstLogins[0].Time, End: burstLogins[len(burstLogins)-1].Time, Logins: burstLogins}
+	}
+	options := DefaultOptions()
+	for _, test := range []struct {
+		name     string
+		logins   []Login
+		findings []Finding
+	}{
+		{"five within the window", logins(0, 2.5, 5, 7.5, 10), []Finding{burst("5 logins in 10m0s", logins(0, 2.5, 5, 7.5, 10))}},
+		{"five just outside the window", logins(0, 2.5, 5, 7.5, 10.05), []Finding{}},
+		{"four within the window", logins(0, 1, 2, 3), []Finding{}},
+		{"longer burst", logins(0, 1, 2, 3, 4, 5, 6), []Finding{burst("7 logins in 6m0s", logins(0, 1, 2, 3, 4, 5, 6))}},
+		{"burst after a quiet login", logins(-60, 0, 1, 2, 3, 4), []Finding{burst("5 logins in 4m0s", logins(-60, 0, 1, 2, 3, 4)[1:])}},
+		{"two bursts", logins(0, 1, 2, 3, 4, 60, 61, 62, 63, 64), []Finding{
+			burst("5 logins in 4m0s", logins(0, 1, 2, 3, 4)),
+			burst("5 logins in 4m0s", logins(0, 1, 2, 3, 4, 60, 61, 62, 63, 64)[5:]),
+		}},
+	} {
+		if findings := detectLoginBursts(test.logins, options); !reflect.DeepEqual(findings, test.findings) {
+			t.Errorf("%s: findings = %+v, want %+v", test.name, findings, test.findings)
+		}
+	}
+}
+
+func TestDetectManyIps(t *testing.T) {
+	hours := func(hours float64, ipAddress string) Login {
+		return unlocated(hours*60, ipAddress)
+	}
+	options := DefaultOptions()
+	for _, test := range []struct {
+		name     string
+		logins   []Login
+		findings []Finding
+	}{
+		{"five within the window", []Login{hours(0, "a"), hours(6, "b"), hours(12, "a"), hours(18, "c"), hours(20, "d"), hours(24, "e")}, []Finding{{
+			Type:        FindingManyIps,
+			Description: "5 distinct IP addresses in 24h0m0s",
+			Start:       start,
+			End:         start.Add(24 * time.Hour),
+			Logins:      []Login{hours(0, "a"), hours(6, "b"), hours(12, "a"), hours(18, "c"), hours(20, "d"), hours(24, "e")},
+		}}},
+		{"five just outside the window", []Login{hours(0, "a"), hours(6, "b"), hours(18, "c"), hours(20, "d"), hours(24.01, "e")}, []Finding{}},
+		{"repeated addresses", []Login{hours(0, "a"), hours(1, "b"), hours(2, "a"), hours(3, "c"), hours(4, "d"), hours(5, "b")}, []Finding{}},
+		{"missing addresses don't count", []Login{hours(0, "a"), hours(1, "b"), hours(2, ""), hours(3, "c"), hours(4, "d")}, []Finding{}},
+		{"window that starts later", []Login{hours(0, "a"), hours(30, "b"), hours(31, "c"), hours(32, "d"), hours(33, "e"), hours(34, "f")}, []Finding{{
+			Type:        FindingManyIps,
+			Description: "5 distinct IP addresses in 4h0m0s",
+			Start:       start.Add(30 * time.Hour),
+			End:         start.Add(34 * time.Hour),
+			Logins:      []Login{hours(30, "b"), hours(31, "c"), hours(32, "d"), hours(33, "e"), hours(34, "f")},
+		}}},
+	} {
+		if findings := detectManyIps(test.logins, options); !reflect.DeepEqual(findings, test.findings) {
+			t.Errorf("%s: findings = %+v, want %+v", test.name, findings, test.findings)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package smtpsink
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+type Message struct {
+	From string
+	To   []string
+	Data string
+}
+
+type Server struct {
+	OnMessage func(Message)
+	mutex     sync.Mutex
+	messages  []Message
+}
+
+func New() *Server {
+	return &Server{messages: []Message{}}
+}
+
+func (s *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(connection)
+	}
+}
+
+func (s *Server) Messages() []Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Message{}, s.messages...)
+}
+
+func (s *Server) handle(connection net.Conn) {
+	defer connection.Close()
+	text := textproto.NewConn(connection)
+	reply := func(code int, message string) {
+		text.PrintfLine("%d %s", code, message)
+	}
+	reply(220, "smtpsink ready")
+	message := Message{}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command, argument, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			reply(250, "smtpsink")
+		case "MAIL":
+			message = Message{From: getAddress(argument)}
+			reply(250, "OK")
+		case "RCPT":
+			message.To = append(message.To, getAddress(argument))
+			reply(250, "OK")
+		case "DATA":
+			reply(354, "End data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			message.Data = string(data)
+			s.mutex.Lock()
+			s.messages = append(s.messages, message)
+			s.mutex.Unlock()
+			if s.OnMessage != nil {
+				s.OnMessage(message)
+			}
+			reply(250, fmt.Sprintf("OK message %d queued", len(s.Messages())))
+		case "RSET":
+			message = Message{}
+			reply(250, "OK")
+		case "NOOP":
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return
+		default:
+			reply(502, "Command not implemented")
+		}
+	}
+}
+
+func getAddress(argument string) string {
+	_, address, found := strings.Cut(argument, ":")
+	if !found {
+		return ""
+	}
+	address, _, _ = strings.Cut(strings.TrimSpace(address), " ")
+	return strings.Trim(address, "<>")
+}
@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"app/extract"
+	"app/fusionauth"
+)
+
+const SignatureHeader = "X-FusionAuth-Signature-JWT"
+const MaxTokenAge = 5 * time.Minute
+const clockSkew = time.Minute
+
+const (
+	EventUserCreate             = "user.create"
+	EventUserRegistrationCreate = "user.registration.create"
+	EventUserLoginSuccess       = "user.login.success"
+	EventUserEmailVerified      = "user.email.verified"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+type Payload struct {
+	Event Event `json:"event"`
+}
+
+type Event struct {
+	Id                   string                   `json:"id,omitempty"`
+	Type                 string                   `json:"type"`
+	CreateInstant        int64                    `json:"createInstant,omitempty"`
+	TenantId             string                   `json:"tenantId,omitempty"`
+	ApplicationId        string                   `json:"applicationId,omitempty"`
+	AuthenticationType   string                   `json:"authenticationType,omitempty"`
+	IdentityProviderId   string                   `json:"identityProviderId,omitempty"`
+	IdentityProviderName string                   `json:"identityProviderName,omitempty"`
+	IpAddress            string                   `json:"ipAddress,omitempty"`
+	Info                 *EventInfo               `json:"info,omitempty"`
+	User                 fusionauth.User          `json:"user"`
+	Registration         *fusionauth.Registration `json:"registration,omitempty"`
+}
+
+type EventInfo struct {
+	IpAddress string `json:"ipAddress,omitempty"`
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+}
+
+type jwtClaims struct {
+	RequestBodySha256 string `json:"request_body_sha256"`
+	IssuedAt          int64  `json:"iat,omitempty"`
+	ExpiresAt         int64  `json:"exp,omitempty"`
+}
+
+func Sign(body []byte, secret []byte, now time.Time) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: "HS256", Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	bodyHash := sha256.Sum256(body)
+	claims, err := json.Marshal(jwtClaims{
+		RequestBodySha256: base64.StdEncoding.EncodeToString(bodyHash[:]),
+		IssuedAt:          now.Unix(),
+		ExpiresAt:         now.Add(MaxTokenAge).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(getSignature(signingInput, secret)), nil
+}
+
+func Verify(body []byte, token string, secret []byte, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: %s header must be a JWT", ErrInvalidSignature, SignatureHeader)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return err
+	}
+	if header.Algorithm != "HS256" {
+		return fmt.Errorf("%w: algorithm %q is not supported, sign webhooks with an HMAC SHA-256 key", ErrInvalidSignature, header.Algorithm)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, getSignature(parts[0]+"."+parts[1], secret)) {
+		return fmt.Errorf("%w: signature doesn't match", ErrInvalidSignature)
+	}
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return err
+	}
+	bodyHash := sha256.Sum256(body)
+	if claims.RequestBodySha256 != base64.StdEncoding.EncodeToString(bodyHash[:]) {
+		return fmt.Errorf("%w: request_body_sha256 doesn't match the body", ErrInvalidSignature)
+	}
+	if claims.ExpiresAt != 0 && now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)) {
+		return fmt.Errorf("%w: token expired at %s", ErrInvalidSignature, time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339))
+	}
+	if claims.IssuedAt != 0 {
+		issuedAt := time.Unix(claims.IssuedAt, 0)
+		if issuedAt.After(now.Add(clockSkew)) || now.Sub(issuedAt) > MaxTokenAge+clockSkew {
+			return fmt.Errorf("%w: token issued at %s is not within %s of now", ErrInvalidSignature, issuedAt.UTC().Format(time.RFC3339), MaxTokenAge)
+		}
+	}
+	return nil
+}
+
+func Parse(body []byte) (Event, error) {
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Event{}, err
+	}
+	if payload.Event.Type == "" {
+		return Event{}, errors.New("event.type is required")
+	}
+	return payload.Event, nil
+}
+
+func IndexById(users []extract.User) map[string]int {
+	indexById := make(map[string]int, len(users))
+	for index, user := range users {
+		indexById[user.Id] = index
+	}
+	return indexById
+}
+
+func Apply(users []extract.User, indexById map[string]int, event Event, options extract.Options) ([]extract.User, bool) {
+	index, exists := indexById[event.User.Id]
+	switch event.Type {
+	case EventUserCreate, EventUserRegistrationCreate:
+		faUser := event.User
+		if event.Registration != nil && !slices.ContainsFunc(faUser.Registrations, func(r fusionauth.Registration) bool {
+			return r.ApplicationId == event.Registration.ApplicationId
+		}) {
+			faUser.Registrations = append(slices.Clone(faUser.Registrations), *event.Registration)
+		}
+		user, ok := extract.NewUser(faUser, options)
+		if !ok {
+			return users, false
+		}
+		if !exists {
+			indexById[user.Id] = len(users)
+			return append(users, user), true
+		}
+		user.Logins, user.LoginDates = users[index].Logins, users[index].LoginDates
+		users[index] = user
+		return users, true
+	case EventUserLoginSuccess:
+		if !exists {
+			user, ok := extract.NewUser(event.User, options)
+			if !ok {
+				return users, false
+			}
+			index = len(users)
+			indexById[user.Id] = index
+			users = append(users, user)
+		}
+		login := extract.NewLogin(fusionauth.LoginRecord{
+			ApplicationId:        event.ApplicationId,
+			ApplicationName:      getApplicationName(users[index], event.ApplicationId),
+			IdentityProviderId:   event.IdentityProviderId,
+			IdentityProviderName: event.IdentityProviderName,
+			Instant:              getInstant(event),
+			IpAddress:            getIpAddress(event),
+		})
+		if slices.Contains(users[index].Logins, login) {
+			return users, false
+		}
+		user := &users[index]
+		user.Logins = append(user.Logins, login)
+		sort.Slice(user.Logins, func(i, j int) bool { return user.Logins[i].Instant < user.Logins[j].Instant })
+		user.LoginDates = []int64{}
+		for _, l := range user.Logins {
+			user.LoginDates = append(user.LoginDates, l.Instant)
+		}
+		return users, true
+	case EventUserEmailVerified:
+		if !exists || users[index].IsVerified {
+			return users, false
+		}
+		users[index].IsVerified = true
+		return users, true
+	}
+	return users, false
+}
+
+func getSignature(signingInput string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, target any) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, err.Error())
+	}
+	if err := json.Unmarshal(content, target); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, err.Error())
+	}
+	return nil
+}
+
+func getApplicationName(user extract.User, applicationId string) string {
+	for _, login := range user.Logins {
+		if login.ApplicationId == applicationId && login.ApplicationName != "" {
+			return login.ApplicationName
+		}
+	}
+	return ""
+}
+
+func getInstant(event Event) int64 {
+	if event.CreateInstant != 0 {
+		return event.CreateInstant
+	}
+	return time.Now().UnixMilli()
+}
+
+func getIpAddress(event Event) string {
+	if event.IpAddress != "" {
+		return event.IpAddress
+	}
+	if event.Info != nil {
+		return event.Info.IpAddress
+	}
+	return ""
+}
@@ -0,0 +1,247 @@
+package webhook_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"app/extract"
+	"app/fakefusionauth"
+	"app/fusionauth"
+	"app/webhook"
+)
+
+const testApplicationId = "test-application"
+
+var secret = []byte("test-secret")
+var now = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func signToken(t *testing.T, header map[string]any, claims map[string]any, secret []byte) string {
+	t.Helper()
+	headerJson, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsJson, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJson) + "." + base64.RawURLEncoding.EncodeToString(claimsJson)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event":{"type":"user.create"}}`)
+	bodyHash := sha256.Sum256(body)
+	hash := base64.StdEncoding.EncodeToString(bodyHash[:])
+	hs256 := map[string]any{"alg": "HS256", "typ": "JWT"}
+	signed, err := webhook.Sign(body, secret, now)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	for _, test := range []struct {
+		name  string
+		body  []byte
+		token string
+		now   time.Time
+		valid bool
+	}{
+		{"signed", body, signed, now, true},
+		{"signed a minute ago", body, signed, now.Add(time.Minute), true},
+		{"tampered body", []byte(`{"event":{"type":"user.delete"}}`), signed, now, false},
+		{"wrong secret", body, signToken(t, hs256, map[string]any{"request_body_sha256": hash}, []byte("other-secret")), now, false},
+		{"unsupported algorithm", body, signToken(t, map[string]any{"alg": "none"}, map[string]any{"request_body_sha256": hash}, secret), now, false},
+		{"not a jwt", body, "token", now, false},
+		{"malformed header", body, "!!!.x.y", now, false},
+		{"expired", body, signed, now.Add(webhook.MaxTokenAge + 2*time.Minute), false},
+		{"issued too long ago", body, signToken(t, hs256, map[string]any{"request_body_sha256": hash, "iat": now.Add(-time.Hour).Unix()}, secret), now, false},
+		{"issued in the future", body, signToken(t, hs256, map[string]any{"request_body_sha256": hash, "iat": now.Add(time.Hour).Unix()}, secret), now, false},
+		{"no iat or exp", body, signToken(t, hs256, map[string]any{"request_body_sha256": hash}, secret), now, true},
+	} {
+		err := webhook.Verify(test.body, test.token, secret, test.now)
+		if test.valid && err != nil {
+			t.Errorf("%s: Verify = %v, want nil", test.name, err)
+		}
+		if !test.valid && !errors.Is(err, webhook.ErrInvalidSignature) {
+			t.Errorf("%s: Verify = %v, want ErrInvalidSignature", test.name, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	options := extract.Options{ApplicationId: testApplicationId}
+	registration := fusionauth.Registration{ApplicationId: testApplicationId, InsertInstant: now.UnixMilli()}
+	user := fusionauth.User{
+		Id:            "user",
+		Email:         "user@example.com",
+		InsertInstant: now.UnixMilli(),
+		Identities:    []fusionauth.Identity{{Primary: true, Type: "email", Value: "user@example.com", VerifiedReason: "Pending"}},
+	}
+	registeredUser := user
+	registeredUser.Registrations = []fusionauth.Registration{registration}
+
+	indexById := webhook.IndexById(nil)
+	users, changed := webhook.Apply(nil, indexById, webhook.Event{Type: webhook.EventUserCreate, User: user}, options)
+	if changed || len(users) != 0 {
+		t.Errorf("user.create without a registration for the application added %+v", users)
+	}
+	users, changed = webhook.Apply(users, indexById, webhook.Event{Type: webhook.EventUserRegistrationCreate, User: user, Registration: &registration}, options)
+	if !changed || len(users) != 1 || users[0].Id != "user" || users[0].IsVerified || indexById["user"] != 0 {
+		t.Fatalf("user.registration.create = %+v, %v, %v, want one unverified user at index 0", users, changed, indexById)
+	}
+	users, changed = webhook.Apply(users, indexById, webhook.Event{Type: webhook.EventUserCreate, User: registeredUser}, options)
+	if !changed || len(users) != 1 {
+		t.Errorf("user.create for a known user = %+v, %v, want the user replaced", users, changed)
+	}
+
+	login := webhook.Event{Type: webhook.EventUserLoginSuccess, CreateInstant: now.Add(time.Hour).UnixMilli(), ApplicationId: testApplicationId, IpAddress: "203.0.113.1", User: registeredUser}
+	users, changed = webhook.Apply(users, indexById, login, options)
+	if !changed || !slices.Equal(users[0].LoginDates, []int64{login.CreateInstant}) || users[0].Logins[0].IpAddress != "203.0.113.1" {
+		t.Errorf("user.login.success = %+v, %v, want one login", users[0], changed)
+	}
+	if users, changed = webhook.Apply(users, indexById, login, options); changed || len(users[0].Logins) != 1 {
+		t.Errorf("repeated user.login.success = %+v, %v, want it ignored", users[0], changed)
+	}
+	earlier := login
+	earlier.CreateInstant = now.UnixMilli()
+	users, _ = webhook.Apply(users, indexById, earlier, options)
+	if !slices.Equal(users[0].LoginDates, []int64{earlier.CreateInstant, login.CreateInstant}) {
+		t.Errorf("login dates = %v, want them sorted", users[0].LoginDates)
+	}
+
+	users, changed = webhook.Apply(users, indexById, webhook.Event{Type: webhook.EventUserEmailVerified, User: registeredUser}, options)
+	if !changed || !users[0].IsVerified {
+		t.Errorf("user.email.verified = %+v, %v, want the user verified", users[0], changed)
+	}
+	if _, changed = webhook.Apply(users, indexById, webhook.Event{Type: webhook.EventUserEmailVerified, User: registeredUser}, options); changed {
+		t.Error("user.email.verified for a verified user changed it again")
+	}
+	if _, changed = webhook.Apply(users, indexById, webhook.Event{Type: "user.delete", User: registeredUser}, options); changed {
+		t.Error("an unsupported event changed the users")
+	}
+}
+
+type eventReceiver struct {
+	mutex   sync.Mutex
+	users   []extract.User
+	index   map[string]int
+	options extract.Options
+	types   []string
+	errors  []error
+}
+
+func (r *eventReceiver) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := webhook.Verify(body, request.Header.Get(webhook.SignatureHeader), secret, time.Now()); err != nil {
+		r.errors = append(r.errors, err)
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	event, err := webhook.Parse(body)
+	if err != nil {
+		r.errors = append(r.errors, err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.types = append(r.types, event.Type)
+	r.users, _ = webhook.Apply(r.users, r.index, event, r.options)
+}
+
+func (r *eventReceiver) waitFor(t *testing.T, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mutex.Lock()
+		received := len(r.types) + len(r.errors)
+		r.mutex.Unlock()
+		if received >= count {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d events, want %d", received, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (r *eventReceiver) findUser(email string) (extract.User, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	index := slices.IndexFunc(r.users, func(user extract.User) bool { return user.Email == email })
+	if index == -1 {
+		return extract.User{}, false
+	}
+	return r.users[index], true
+}
+
+func TestEventsFromFakeFusionAuth(t *testing.T) {
+	options := extract.Options{ApplicationId: testApplicationId}
+	receiver := &eventReceiver{options: options}
+	receiverServer := httptest.NewServer(receiver)
+	defer receiverServer.Close()
+	server := fakefusionauth.New(fakefusionauth.Options{WebhookUrl: receiverServer.URL, WebhookSecret: string(secret)})
+	fusionAuth := httptest.NewServer(server)
+	defer fusionAuth.Close()
+
+	unverified := server.AddUser(fusionauth.User{Email: "unverified@example.com", Registrations: []fusionauth.Registration{{ApplicationId: testApplicationId}}})
+	existing, _ := extract.NewUser(unverified, options)
+	receiver.users = []extract.User{existing}
+	receiver.index = webhook.IndexById(receiver.users)
+
+	post(t, fusionAuth.URL+"/api/user/registration", fusionauth.RegistrationRequest{User: fusionauth.User{Email: "new@example.com"}, Registration: fusionauth.Registration{ApplicationId: testApplicationId}})
+	receiver.waitFor(t, 2)
+	if user, exists := receiver.findUser("new@example.com"); !exists || !user.IsVerified {
+		t.Errorf("registered user = %+v, %v, want a verified user", user, exists)
+	}
+	post(t, fusionAuth.URL+"/api/login", fusionauth.LoginRequest{LoginId: "new@example.com", ApplicationId: testApplicationId, IpAddress: "203.0.113.7"})
+	receiver.waitFor(t, 3)
+	if user, _ := receiver.findUser("new@example.com"); len(user.Logins) != 1 || user.Logins[0].IpAddress != "203.0.113.7" {
+		t.Errorf("logins after signing in = %+v, want one from 203.0.113.7", user.Logins)
+	}
+	post(t, fusionAuth.URL+"/api/user/verify-email", fusionauth.VerifyEmailRequest{UserId: unverified.Id})
+	receiver.waitFor(t, 4)
+	if user, _ := receiver.findUser("unverified@example.com"); !user.IsVerified {
+		t.Error("user is still unverified after user.email.verified")
+	}
+
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	if len(receiver.errors) != 0 {
+		t.Errorf("receiver rejected events: %v", receiver.errors)
+	}
+	types := slices.Sorted(slices.Values(receiver.types))
+	want := []string{webhook.EventUserCreate, webhook.EventUserEmailVerified, webhook.EventUserLoginSuccess, webhook.EventUserRegistrationCreate}
+	if !slices.Equal(types, want) {
+		t.Errorf("received events %v, want %v", types, want)
+	}
+}
+
+func post(t *testing.T, url string, request any) {
+	t.Helper()
+	body, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("POST %s: status %d", url, response.StatusCode)
+	}
+}